@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/MaximK0valev/go-task-scheduler/pkg/api"
 	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
@@ -15,7 +16,7 @@ import (
 // Application entry point.
 //
 // Loads configuration from environment (.env is optional), initializes the database
-// and starts the HTTP server.
+// and starts the HTTP server. "migrate status|up|down [N]" manages the schema instead (see runMigrate).
 func main() {
 	// Load environment variables from .env if present.
 	// If the file is missing, the application falls back to system environment variables.
@@ -25,15 +26,34 @@ func main() {
 
 	config := api.GetConfig()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(config.TodoDBDriver, config.TodoDBDSN, os.Args[2:]))
+	}
+
 	// Debug-print effective configuration (useful during local development).
 	// Note: printing secrets (password/token) is not recommended for production.
 	fmt.Printf("Конфигурация приложения:\n")
 	fmt.Printf("TODO_PASSWORD = %s\n", config.TodoPassword)
 	fmt.Printf("TODO_PORT = %s\n", config.TodoPort)
 	fmt.Printf("TODO_DBFILE = %s\n", config.TodoDBFile)
+	fmt.Printf("TODO_DB_DRIVER = %s\n", config.TodoDBDriver)
+	fmt.Printf("TODO_TZ = %s\n", config.TodoTZ)
+	fmt.Printf("TODO_TRASH_DAYS = %s\n", config.TodoTrashDays)
+
+	// An unknown time zone would silently fall back to the server local zone.
+	if config.TodoTZ != "" {
+		if _, err := time.LoadLocation(config.TodoTZ); err != nil {
+			fmt.Printf("Некорректный часовой пояс TODO_TZ: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if _, err := config.TrashRetention(); err != nil {
+		fmt.Printf("Некорректный срок хранения корзины TODO_TRASH_DAYS: %v\n", err)
+		os.Exit(1)
+	}
 
-	// Initialize SQLite database and install schema on first run.
-	if err := db.Init(config.TodoDBFile); err != nil {
+	// Initialize the database and apply pending migrations.
+	if err := db.Init(config.TodoDBDriver, config.TodoDBDSN); err != nil {
 		fmt.Printf("Ошибка инициализации базы данных: %v\n", err)
 		os.Exit(1)
 	}
@@ -46,5 +66,5 @@ func main() {
 	defer db.DB.Close()
 
 	fmt.Println("База данных подключена успешно")
-	server.Run()
+	server.Run(db.NewSQLStore(db.DB, config.TodoDBDriver))
 }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+)
+
+// runMigrate implements the "migrate" subcommand and returns the process exit code.
+//
+// Usage:
+//
+//	migrate status     list migrations and whether they are applied
+//	migrate up         apply all pending migrations
+//	migrate down [N]   roll back N latest migrations (default 1)
+func runMigrate(driver, dsn string, args []string) int {
+	if len(args) == 0 {
+		fmt.Println("Использование: migrate status | up | down [N]")
+		return 2
+	}
+
+	if err := db.Open(driver, dsn); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer db.DB.Close()
+
+	switch args[0] {
+	case "status":
+		states, err := db.MigrationStatus()
+		if err != nil {
+			fmt.Printf("Ошибка получения статуса миграций: %v\n", err)
+			return 1
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ВЕРСИЯ\tИМЯ\tПРИМЕНЕНА")
+		for _, s := range states {
+			applied := s.AppliedAt
+			if applied == "" {
+				applied = "-"
+			}
+			fmt.Fprintf(tw, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		tw.Flush()
+	case "up":
+		n, err := db.MigrateUp()
+		fmt.Printf("Применено миграций: %d\n", n)
+		if err != nil {
+			fmt.Printf("Ошибка миграции: %v\n", err)
+			return 1
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				fmt.Printf("Некорректное число шагов отката: %q\n", args[1])
+				return 2
+			}
+		}
+		n, err := db.MigrateDown(steps)
+		fmt.Printf("Откачено миграций: %d\n", n)
+		if err != nil {
+			fmt.Printf("Ошибка отката: %v\n", err)
+			return 1
+		}
+	default:
+		fmt.Printf("Неизвестная команда migrate %q\n", args[0])
+		return 2
+	}
+	return 0
+}
@@ -15,7 +15,7 @@ import (
 // addTaskHandler creates a new task.
 //
 // Method: POST /api/task
-// Body:   JSON (db.Task)
+// Body:   JSON (db.Task), optionally with the initial checklist
 // Result: {"id": "..."}
 func addTaskHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -35,65 +35,129 @@ func addTaskHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Normalize the date: set default date, prevent dates in the past,
 	// and for repeating tasks calculate the next occurrence.
-	if err := checkDate(&task); err != nil {
+	task.Anchor = ""
+	if err := checkDate(&task, storeFor(r)); err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if err := checkTags(&task); err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if err := checkPriority(task.Priority); err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	for _, item := range task.Checklist {
+		if err := checkItem(item); err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	if err := checkProject(storeFor(r), task.ProjectID, ""); err != nil {
 		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
 
-	id, err := db.AddTask(&task)
+	id, err := storeFor(r).Add(&task)
 	if err != nil {
 		writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Ошибка сохранения задачи: " + err.Error()})
 		return
 	}
+	taskID := strconv.FormatInt(id, 10)
+	recordUndo(r, "add", taskID, 1, undoAdd(taskID))
 
-	writeJson(w, http.StatusOK, map[string]string{"id": strconv.FormatInt(id, 10)})
+	writeJson(w, http.StatusOK, map[string]string{"id": taskID})
 }
 
 // checkDate validates and normalizes task.Date.
 //
+// "Today" is the current date in the task's time zone (task.TZ or TODO_TZ).
+//
 // Rules:
 //   - If date is empty, it defaults to "today".
 //   - Non-repeating tasks cannot be scheduled in the past: past dates are replaced with "today".
-//   - Repeating tasks scheduled for today or earlier are moved to the next occurrence.
-func checkDate(task *db.Task) error {
-	now := time.Now()
+//   - A repeating task continues its series from task.Anchor if the caller has set it
+//     (the occurrence the current date was rolled from), otherwise from its date.
+//   - Repeating tasks scheduled before today are moved to the next occurrence
+//     (today counts as upcoming until the task time, if one is set).
+//   - An RRULE with COUNT is stored as sent; repeat_count is set to the occurrences of COUNT
+//     left from the new date on (a smaller repeat_count sent by the client is kept).
+//     The stored date moves forward with every "done", so the series is counted down
+//     in repeat_count rather than from the date: saving a task back as it was read
+//     keeps both the rule and the occurrences left.
+//   - Finally the date is moved off weekends and holidays according to task.Roll,
+//     keeping the occurrence in task.Anchor if it has moved (see db.Task.Anchor),
+//     and the repeat end conditions (repeat_until, repeat_count) are validated.
+func checkDate(task *db.Task, holidays db.HolidayStore) error {
+	if err := checkTime(task); err != nil {
+		return err
+	}
+	if err := checkRoll(task.Roll); err != nil {
+		return err
+	}
+
+	now := time.Now().In(taskLocation(task))
+	today := now.Format(DateFormat)
 
 	if task.Date == "" {
-		task.Date = now.Format(DateFormat)
+		task.Date = today
 	}
 
-	t, err := time.Parse(DateFormat, task.Date)
+	_, err := time.Parse(DateFormat, task.Date)
 	if err != nil {
 		return fmt.Errorf("некорректная дата: %v", err)
 	}
 
-	// startOfDay returns midnight for the given date.
-	startOfDay := func(t time.Time) time.Time {
-		y, m, d := t.Date()
-		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
-	}
-
 	if task.Repeat != "" {
-		next, err := NextDate(now, task.Date, task.Repeat)
+		rule, err := ParseRepeat(task.Repeat)
 		if err != nil {
 			return fmt.Errorf("некорректное правило повторения: %v", err)
 		}
+		// Store the rule in its canonical form.
+		task.Repeat = rule.String()
+
+		start := task.Date
+		if task.Anchor != "" {
+			start = task.Anchor
+		}
+		// If the task is due in the past, move it to the next occurrence.
+		occurrence := start
+		if task.Date < today {
+			occurrence, err = NextDate(taskNow(now, task), start, task.Repeat, holidays)
+			if err != nil {
+				return fmt.Errorf("некорректное правило повторения: %v", err)
+			}
+		}
+		task.Date = occurrence
 
-		// If the initial date is not after today, move it forward.
-		if !t.After(startOfDay(now)) {
-			task.Date = next
+		if rule.RRule != nil && rule.RRule.Count > 0 {
+			left := countLeft(rule, start, occurrence)
+			if task.RepeatCount == 0 || left < task.RepeatCount {
+				task.RepeatCount = left
+			}
 		}
 	} else {
 		// Non-repeating task: do not allow dates strictly before today.
-		if t.Before(startOfDay(now)) {
-			task.Date = now.Format(DateFormat)
+		if task.Date < today {
+			task.Date = today
 		}
 	}
 
-	return nil
+	rolled, err := RollDate(now, task.Date, task.Roll, holidays)
+	if err != nil {
+		return err
+	}
+	task.Anchor = ""
+	if task.Repeat != "" && rolled != task.Date {
+		task.Anchor = task.Date
+	}
+	task.Date = rolled
+	return checkRepeatEnd(task)
 }
 
 // getTaskHandler returns a single task by ID.
+// The ETag header carries the task version to be sent back in If-Match when the task is updated.
 //
 // Method: GET /api/task?id=<id>
 func getTaskHandler(w http.ResponseWriter, r *http.Request) {
@@ -102,18 +166,26 @@ func getTaskHandler(w http.ResponseWriter, r *http.Request) {
 		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан идентификатор"})
 		return
 	}
-	task, err := db.GetTask(id)
+	task, err := store.Get(id)
 	if err != nil {
 		writeJson(w, http.StatusNotFound, map[string]string{"error": "Задача не найдена"})
 		return
 	}
+	w.Header().Set("ETag", taskETag(task.Version))
 	writeJson(w, http.StatusOK, task)
 }
 
 // updateTaskHandler updates an existing task.
 //
 // Method: PUT /api/task
-// Body:   JSON (db.Task with non-empty ID)
+// Body:   JSON (db.Task with non-empty ID); tags are kept if "tags" is absent
+// and removed if it is an empty list. The checklist is changed via /api/task/checklist only.
+//
+// The version being edited must be given in the If-Match header (the ETag of GET /api/task,
+// "*" to overwrite any version) or in the "version" field; without either 428 is returned.
+// If the task has changed since, nothing is saved and the response is 412 (If-Match)
+// or 409 (version) with {"error": "...", "task": <current task>}.
+// On success the ETag header carries the new version.
 func updateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	var t db.Task
 	err := json.NewDecoder(r.Body).Decode(&t)
@@ -125,6 +197,17 @@ func updateTaskHandler(w http.ResponseWriter, r *http.Request) {
 		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан идентификатор"})
 		return
 	}
+	version, ifMatch, err := ifMatchVersion(r)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if ifMatch {
+		t.Version = version
+	} else if t.Version <= 0 {
+		writeJson(w, http.StatusPreconditionRequired, map[string]string{"error": "Не указана версия задачи: передайте заголовок If-Match или поле version"})
+		return
+	}
 	if t.Title == "" {
 		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан заголовок задачи"})
 		return
@@ -137,26 +220,103 @@ func updateTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A task saved with its date, rule and roll policy unchanged keeps the occurrence
+	// its date was rolled from; otherwise the date sent is the new occurrence.
+	current := ""
+	t.Anchor = ""
+	old, err := store.Get(t.ID)
+	if err == nil {
+		current = old.ProjectID
+		if t.Date == old.Date && t.Repeat == old.Repeat && t.Roll == old.Roll {
+			t.Anchor = old.Anchor
+		}
+	}
+
 	// Normalize/validate date for the updated task.
-	err = checkDate(&t)
+	err = checkDate(&t, storeFor(r))
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	err = checkTags(&t)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	err = checkPriority(t.Priority)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	err = checkProject(storeFor(r), t.ProjectID, current)
 	if err != nil {
 		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
 
-	err = db.UpdateTask(&t)
+	err = storeFor(r).Update(&t)
 	if err != nil {
-		if err.Error() == "задача не найдена" {
+		switch err.Error() {
+		case "задача не найдена":
 			writeJson(w, http.StatusNotFound, map[string]string{"error": "Задача не найдена"})
-		} else {
+		case "версия задачи устарела":
+			writeVersionConflict(w, storeFor(r), t.ID, ifMatch)
+		default:
 			writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Ошибка обновления задачи: " + err.Error()})
 		}
 		return
 	}
+	// The snapshot is undoable only if it is the version this update replaced.
+	if old != nil && old.Version+1 == t.Version {
+		recordUndo(r, "update", old.ID, t.Version, undoUpdate(old))
+	}
+	w.Header().Set("ETag", taskETag(t.Version))
 	writeJson(w, http.StatusOK, struct{}{})
 }
 
-// deleteTaskHandler deletes a task by ID.
+// taskETag returns the entity tag of a task version.
+func taskETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatchVersion reads the task version from the If-Match header; ok is false if there is
+// no header. "*" matches any version and is returned as 0.
+func ifMatchVersion(r *http.Request) (version int64, ok bool, err error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" {
+		return 0, false, nil
+	}
+	if value == "*" {
+		return 0, true, nil
+	}
+	tag := strings.TrimPrefix(value, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false, fmt.Errorf("некорректный заголовок If-Match: %s", value)
+	}
+	version, err = strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, false, fmt.Errorf("некорректный заголовок If-Match: %s", value)
+	}
+	return version, true, nil
+}
+
+// writeVersionConflict reports an update of a stale task version with the current copy of the task:
+// 412 if the version came in If-Match, 409 if it came in the body.
+func writeVersionConflict(w http.ResponseWriter, tasks db.TaskStore, id string, ifMatch bool) {
+	status := http.StatusConflict
+	if ifMatch {
+		status = http.StatusPreconditionFailed
+	}
+	current, err := tasks.Get(id)
+	if err != nil {
+		writeJson(w, http.StatusNotFound, map[string]string{"error": "Задача не найдена"})
+		return
+	}
+	w.Header().Set("ETag", taskETag(current.Version))
+	writeJson(w, status, map[string]any{"error": "Задача изменена после загрузки: проверьте её текущую версию", "task": current})
+}
+
+// deleteTaskHandler moves a task to the trash by ID (see trashHandler).
 //
 // Method: DELETE /api/task?id=<id>
 func deleteTaskHandler(w http.ResponseWriter, r *http.Request) {
@@ -165,7 +325,17 @@ func deleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан идентификатор"})
 		return
 	}
-	err := db.DeleteTask(id)
+	old, err := store.Get(id)
+	if err != nil {
+		writeJson(w, http.StatusNotFound, map[string]string{"error": "Задача не найдена"})
+		return
+	}
+	dependents, err := store.Dependents(old.ID)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	err = storeFor(r).Delete(old.ID)
 	if err != nil {
 		if err.Error() == "задача не найдена" {
 			writeJson(w, http.StatusNotFound, map[string]string{"error": "Задача не найдена"})
@@ -174,16 +344,27 @@ func deleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	recordUndo(r, "delete", old.ID, old.Version+1, undoDelete(old, dependents))
 	writeJson(w, http.StatusOK, struct{}{})
 }
 
 // taskDone marks a task as completed.
 //
 // Behavior:
-//   - For non-repeating tasks: delete from DB.
-//   - For repeating tasks: compute next date and update the task.
+//   - A task with required checklist items that are not done is not completed (409 Conflict).
+//   - A task blocked by other tasks is not completed (409 Conflict) unless force=true.
+//   - Every completion is recorded in the task history (see taskHistoryHandler)
+//     in the same transaction as the deletion or the date update below.
+//   - The action can be undone (see undoHandler): the task gets back its exact previous date.
+//   - For non-repeating tasks: move to the trash.
+//   - For repeating tasks: compute next date (rolled off weekends/holidays
+//     according to task.Roll), update the task and reset its checklist.
+//   - When the series ends (repeat_until is passed, repeat_count is used up
+//     or the RRULE has no more occurrences), the task is moved to the trash.
+//   - The task is completed only if it has not changed since it was read (409 Conflict
+//     otherwise), so concurrent requests cannot complete the same occurrence twice.
 //
-// Method: POST /api/task/done?id=<id>
+// Method: POST /api/task/done?id=<id>&force=true
 func taskDone(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Метод не поддерживается"})
@@ -194,95 +375,141 @@ func taskDone(w http.ResponseWriter, r *http.Request) {
 		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан идентификатор"})
 		return
 	}
-	task, err := db.GetTask(id)
+	force := false
+	if v := r.URL.Query().Get("force"); v != "" {
+		var err error
+		force, err = strconv.ParseBool(v)
+		if err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]string{"error": "неверный параметр force: допустимо true или false"})
+			return
+		}
+	}
+	task, err := store.Get(id)
 	if err != nil {
 		writeJson(w, http.StatusNotFound, map[string]string{"error": "Задача не найдена"})
 		return
 	}
-	if task.Repeat == "" {
-		err = db.DeleteTask(id)
-		if err != nil {
-			writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Ошибка удаления: " + err.Error()})
-			return
-		}
-		writeJson(w, http.StatusOK, struct{}{})
+	if task.Blocked && !force {
+		writeJson(w, http.StatusConflict, map[string]string{"error": "Задача заблокирована задачами: " + strings.Join(task.BlockedBy, ", ")})
+		return
+	}
+	if open := openRequiredItems(task); len(open) > 0 {
+		writeJson(w, http.StatusConflict, map[string]string{"error": "Не выполнены обязательные пункты: " + strings.Join(open, ", ")})
 		return
 	}
 
-	nextdata, err := NextDate(time.Now(), task.Date, task.Repeat)
+	now := time.Now()
+	nextdata, anchor, err := nextOccurrence(now, task, storeFor(r))
 	if err != nil {
 		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не удалось раcчитать следующую дату: " + err.Error()})
 		return
 	}
-	err = db.UpdateDate(nextdata, id)
+
+	dependents, err := store.Dependents(task.ID)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	completionID, err := storeFor(r).Complete(task.ID, task.Version, nextdata, anchor, now)
 	if err != nil {
-		writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Не удалось обновить дату: " + err.Error()})
+		switch err.Error() {
+		case "задача не найдена":
+			writeJson(w, http.StatusNotFound, map[string]string{"error": "Задача не найдена"})
+		case "версия задачи устарела":
+			writeJson(w, http.StatusConflict, map[string]string{"error": "Задача изменена другим запросом: обновите её и повторите"})
+		default:
+			writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Ошибка завершения задачи: " + err.Error()})
+		}
 		return
 	}
+	recordUndo(r, "done", task.ID, task.Version+1, undoDone(task, dependents, strconv.FormatInt(completionID, 10)))
 	writeJson(w, http.StatusOK, struct{}{})
 }
 
-// checkRepeat validates repeat rule format.
-//
-// Supported formats:
-//   - d <N>           every N days (1..400)
-//   - w <list>        weekly on weekdays (1..7), e.g. "w 1,3,5"
-//   - m <days> [mons] monthly on day numbers, e.g. "m 1,15" or "m -1" (last day)
-//     optional months list: "m 1,15 1,6,12"
-//   - y               yearly
-func checkRepeat(repeat string) error {
-	if repeat == "" {
-		return nil
+// nextOccurrence returns the date a task moves to when it is done and the occurrence
+// it was rolled from (empty if the date is not rolled, see db.Task.Anchor),
+// or empty strings if the task does not repeat or its series has ended.
+// The next occurrence follows the current one, not the rolled date.
+func nextOccurrence(now time.Time, task *db.Task, holidays db.HolidayStore) (string, string, error) {
+	if task.Repeat == "" || task.RepeatCount == 1 {
+		return "", "", nil
 	}
 
-	parts := strings.Fields(repeat)
-	if len(parts) < 1 {
-		return errors.New("некорректный repeat")
+	occurrence := task.Date
+	if task.Anchor != "" {
+		occurrence = task.Anchor
 	}
+	now = taskNow(now, task)
+	next, err := NextDate(now, occurrence, task.Repeat, holidays)
+	if errors.Is(err, ErrSeriesEnded) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if task.RepeatUntil != "" && next > task.RepeatUntil {
+		return "", "", nil
+	}
+	rolled, err := RollDate(now, next, task.Roll, holidays)
+	if err != nil || rolled == next {
+		return rolled, "", err
+	}
+	return rolled, next, nil
+}
 
-	unit := parts[0]
-
-	switch unit {
-	case "d":
-		if len(parts) != 2 {
-			return errors.New("некорректный формат для d")
-		}
-		num, err := strconv.Atoi(parts[1])
-		if err != nil || num <= 0 {
-			return errors.New("некорректное число repeat")
-		}
-
-	case "w":
-		if len(parts) < 2 {
-			return errors.New("отсутствуют дни недели для w")
-		}
-		dayStrs := strings.Split(parts[1], ",")
-		for _, dayStr := range dayStrs {
-			dayNum, err := strconv.Atoi(dayStr)
-			if err != nil || dayNum < 1 || dayNum > 7 {
-				return errors.New("некорректный день недели")
-			}
+// countLeft returns the number of occurrences of an RRULE with COUNT left from date on
+// (date included) for the series starting at start; date must be an occurrence of the series.
+func countLeft(rule RepeatRule, start, date string) int {
+	d, _ := time.Parse(DateFormat, start)
+	left := rule.RRule.Count
+	for d.Format(DateFormat) < date && left > 1 {
+		next, ok := rule.Next(d)
+		if !ok {
+			break
 		}
+		d = next
+		left--
+	}
+	return left
+}
 
-	case "m":
-		if len(parts) < 2 {
-			return errors.New("отсутствуют дни месяца для m")
-		}
-		dayStrs := strings.Split(parts[1], ",")
-		for _, dayStr := range dayStrs {
-			dayNum, err := strconv.Atoi(dayStr)
-			if err != nil || (dayNum < -2 || dayNum > 31 || dayNum == 0) {
-				return errors.New("некорректный день месяца")
-			}
+// checkRepeatEnd validates repeat_until and repeat_count of a task.
+func checkRepeatEnd(task *db.Task) error {
+	if task.RepeatUntil == "" && task.RepeatCount == 0 {
+		return nil
+	}
+	if task.Repeat == "" {
+		return errors.New("repeat_until и repeat_count допустимы только для повторяющихся задач")
+	}
+	if task.RepeatCount < 0 {
+		return errors.New("repeat_count не может быть отрицательным")
+	}
+	if task.RepeatUntil != "" {
+		if _, err := time.Parse(DateFormat, task.RepeatUntil); err != nil {
+			return fmt.Errorf("некорректная дата repeat_until: %v", err)
 		}
-
-	case "y":
-		if len(parts) != 1 {
-			return errors.New("некорректный формат для d/y")
+		if task.RepeatUntil < task.Date {
+			return errors.New("repeat_until не может быть раньше даты задачи")
 		}
-	default:
-		return errors.New("некорректная единица repeat")
 	}
+	return nil
+}
 
+// checkPriority validates a task priority: 1 (low) to db.MaxPriority (urgent), or 0 for none.
+func checkPriority(priority int) error {
+	if priority < 0 || priority > db.MaxPriority {
+		return fmt.Errorf("некорректный приоритет %d: допустимо от 1 до %d (0 — без приоритета)", priority, db.MaxPriority)
+	}
 	return nil
 }
+
+// checkRepeat validates repeat rule format (see ParseRepeat).
+// An empty rule means the task does not repeat.
+func checkRepeat(repeat string) error {
+	if repeat == "" {
+		return nil
+	}
+	_, err := ParseRepeat(repeat)
+	return err
+}
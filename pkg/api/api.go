@@ -3,24 +3,74 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
 )
 
-// Init registers all HTTP routes for the application.
+// store is the task storage used by the handlers.
+// It is set by NewMux, so only one store can be served per process.
+var store db.TaskStore
+
+// storeFor returns the store view recording the writes of a request
+// in the audit log under the request actor (see requestActor).
+func storeFor(r *http.Request) db.TaskStore {
+	return store.As(requestActor(r))
+}
+
+// Init registers all HTTP routes of the application on http.DefaultServeMux.
+func Init(tasks db.TaskStore) {
+	http.Handle("/api/", NewMux(tasks))
+}
+
+// NewMux returns a mux with all /api routes served from the given task store.
+// It allows running the API against any TaskStore, e.g. db.NewMemoryStore in tests.
 //
 // Public endpoints:
 //   - POST /api/signin
 //   - GET  /api/nextdate
+//   - GET  /api/occurrences
 //
 // Protected endpoints (require AuthMiddleware):
 //   - /api/task (CRUD)
 //   - GET /api/tasks
 //   - POST /api/task/done
-func Init() {
-	http.HandleFunc("/api/signin", SigninHandler)
-	http.HandleFunc("/api/nextdate", nextDayHandler)
-	http.HandleFunc("/api/task", AuthMiddleware(taskHandler))
-	http.HandleFunc("/api/tasks", AuthMiddleware(tasksHandler))
-	http.HandleFunc("/api/task/done", AuthMiddleware(taskDoneHandler))
+//   - POST /api/task/move
+//   - /api/task/dependencies (GET, POST, DELETE)
+//   - GET /api/task/history, GET /api/completions
+//   - /api/trash (GET, DELETE), POST /api/trash/restore
+//   - POST /api/undo
+//   - GET /api/audit
+//   - /api/task/checklist (CRUD), POST /api/task/checklist/toggle, POST /api/task/checklist/reorder
+//   - /api/holidays (CRUD), POST /api/holidays/import
+//   - /api/tags (CRUD)
+//   - /api/projects (CRUD)
+func NewMux(tasks db.TaskStore) *http.ServeMux {
+	store = tasks
+	undo = newUndoHistory()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/signin", SigninHandler)
+	mux.HandleFunc("/api/nextdate", nextDayHandler)
+	mux.HandleFunc("/api/occurrences", occurrencesHandler)
+	mux.HandleFunc("/api/task", AuthMiddleware(taskHandler))
+	mux.HandleFunc("/api/tasks", AuthMiddleware(tasksHandler))
+	mux.HandleFunc("/api/task/done", AuthMiddleware(taskDoneHandler))
+	mux.HandleFunc("/api/task/move", AuthMiddleware(taskMoveHandler))
+	mux.HandleFunc("/api/task/dependencies", AuthMiddleware(dependenciesHandler))
+	mux.HandleFunc("/api/task/history", AuthMiddleware(taskHistoryHandler))
+	mux.HandleFunc("/api/completions", AuthMiddleware(completionsHandler))
+	mux.HandleFunc("/api/trash", AuthMiddleware(trashHandler))
+	mux.HandleFunc("/api/trash/restore", AuthMiddleware(trashRestoreHandler))
+	mux.HandleFunc("/api/undo", AuthMiddleware(undoHandler))
+	mux.HandleFunc("/api/audit", AuthMiddleware(auditHandler))
+	mux.HandleFunc("/api/task/checklist", AuthMiddleware(checklistHandler))
+	mux.HandleFunc("/api/task/checklist/toggle", AuthMiddleware(checklistToggleHandler))
+	mux.HandleFunc("/api/task/checklist/reorder", AuthMiddleware(checklistReorderHandler))
+	mux.HandleFunc("/api/holidays", AuthMiddleware(holidaysHandler))
+	mux.HandleFunc("/api/holidays/import", AuthMiddleware(importHolidaysHandler))
+	mux.HandleFunc("/api/tags", AuthMiddleware(tagsHandler))
+	mux.HandleFunc("/api/projects", AuthMiddleware(projectsHandler))
+	return mux
 }
 
 // taskHandler is a multiplexer for CRUD operations on a single task.
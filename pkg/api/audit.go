@@ -0,0 +1,66 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+)
+
+// Page size limits of GET /api/audit.
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// AuditResp is a response wrapper for GET /api/audit.
+type AuditResp struct {
+	Entries []*db.AuditEntry `json:"entries"`
+}
+
+// auditHandler returns the audit log of task changes, the latest first.
+//
+// Method: GET /api/audit
+//
+// Query params (all optional):
+//   - task_id: changes of one task (kept after the task is purged)
+//   - actor:   changes recorded under one actor, e.g. "system" for the scheduled trash cleanup;
+//     actors are names self-declared at sign-in, not authenticated users
+//   - action:  add, update, delete, done, reschedule, move, tag, checklist, link, unlink, restore, purge or undo
+//   - from, to (YYYYMMDD, inclusive): days in the configured time zone
+//   - limit:   number of entries, 1..1000 (default 100)
+func auditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Метод не поддерживается"})
+		return
+	}
+	q := r.URL.Query()
+	filter := db.AuditFilter{
+		TaskID: q.Get("task_id"),
+		Actor:  q.Get("actor"),
+		Action: q.Get("action"),
+		Limit:  defaultAuditLimit,
+	}
+	var err error
+	filter.From, filter.To, err = dayRange(r)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			writeJson(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("неверный параметр limit: ожидается число от 1 до %d", maxAuditLimit)})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := store.AuditLog(filter)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, AuditResp{Entries: entries})
+}
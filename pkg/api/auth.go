@@ -1,13 +1,16 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -15,13 +18,21 @@ import (
 // Config contains runtime settings loaded from environment variables.
 //
 // Environment variables:
-//   - TODO_PASSWORD: password used for login and JWT signing key
-//   - TODO_PORT:     HTTP server port
-//   - TODO_DBFILE:   path to SQLite database file
+//   - TODO_PASSWORD:   password used for login and JWT signing key
+//   - TODO_PORT:       HTTP server port
+//   - TODO_DBFILE:     path to SQLite database file
+//   - TODO_DB_DRIVER:  storage backend, "sqlite" (default) or "postgres"
+//   - TODO_DB_DSN:     connection string for the driver (defaults to TODO_DBFILE for SQLite)
+//   - TODO_TZ:         default IANA time zone for tasks, e.g. "Europe/Moscow" (server local zone if empty)
+//   - TODO_TRASH_DAYS: days deleted tasks are kept in the trash before they are purged (default 30, 0 keeps them forever)
 type Config struct {
-	TodoPassword string
-	TodoPort     string
-	TodoDBFile   string
+	TodoPassword  string
+	TodoPort      string
+	TodoDBFile    string
+	TodoDBDriver  string
+	TodoDBDSN     string
+	TodoTZ        string
+	TodoTrashDays string
 }
 
 var (
@@ -37,9 +48,13 @@ var (
 func GetConfig() *Config {
 	configOnce.Do(func() {
 		appConfig = &Config{
-			TodoPassword: os.Getenv("TODO_PASSWORD"),
-			TodoPort:     os.Getenv("TODO_PORT"),
-			TodoDBFile:   os.Getenv("TODO_DBFILE"),
+			TodoPassword:  os.Getenv("TODO_PASSWORD"),
+			TodoPort:      os.Getenv("TODO_PORT"),
+			TodoDBFile:    os.Getenv("TODO_DBFILE"),
+			TodoDBDriver:  os.Getenv("TODO_DB_DRIVER"),
+			TodoDBDSN:     os.Getenv("TODO_DB_DSN"),
+			TodoTZ:        os.Getenv("TODO_TZ"),
+			TodoTrashDays: os.Getenv("TODO_TRASH_DAYS"),
 		}
 
 		// Default values for local development.
@@ -52,16 +67,52 @@ func GetConfig() *Config {
 		if appConfig.TodoDBFile == "" {
 			appConfig.TodoDBFile = "scheduler.db"
 		}
+		if appConfig.TodoDBDriver == "" {
+			appConfig.TodoDBDriver = "sqlite"
+		}
+		if appConfig.TodoDBDSN == "" && appConfig.TodoDBDriver == "sqlite" {
+			appConfig.TodoDBDSN = appConfig.TodoDBFile
+		}
+		if appConfig.TodoTrashDays == "" {
+			appConfig.TodoTrashDays = "30"
+		}
 	})
 	return appConfig
 }
 
+// Location returns the default time zone for tasks.
+// An empty or unknown TODO_TZ falls back to the server local zone.
+func (c *Config) Location() *time.Location {
+	if c.TodoTZ == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(c.TodoTZ)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// TrashRetention returns how long deleted tasks are kept in the trash;
+// zero means they are never purged automatically.
+func (c *Config) TrashRetention() (time.Duration, error) {
+	days, err := strconv.Atoi(c.TodoTrashDays)
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("ожидается неотрицательное число дней, получено %q", c.TodoTrashDays)
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
 // Claims describes JWT payload used by this app.
 //
 // PasswordHash is used to invalidate all previously issued tokens
-// when the configured password changes.
+// when the configured password changes. Name is the name given at sign-in
+// and recorded as the actor of task changes (see requestActor). It is self-declared:
+// everyone signs in with the same password, so the name is not authenticated
+// and the token has no subject identifying a user.
 type Claims struct {
 	PasswordHash string `json:"pwd_hash"`
+	Name         string `json:"name,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -69,6 +120,7 @@ type Claims struct {
 //   - Cookie "token", or
 //   - Authorization: Bearer <token>
 //
+// The claims of a valid token are passed to the handler in the request context.
 // If TODO_PASSWORD is empty, authentication is considered disabled
 // and requests are passed through.
 func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
@@ -81,36 +133,64 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		var tokenString string
-		cookie, err := r.Cookie("token")
-		if err == nil {
-			tokenString = cookie.Value
-		}
-
-		if tokenString == "" {
-			authHeader := r.Header.Get("Authorization")
-			if authHeader != "" && strings.HasPrefix(authHeader, "Bearer ") {
-				tokenString = strings.TrimPrefix(authHeader, "Bearer ")
-			}
-		}
-
+		tokenString := requestToken(r)
 		if tokenString == "" {
 			http.Error(w, "Требуется аутентификация", http.StatusUnauthorized)
 			return
 		}
 
-		valid := validateToken(tokenString, password)
+		claims, valid := validateToken(tokenString, password)
 		if !valid {
 			http.Error(w, "Требуется аутентификация", http.StatusUnauthorized)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
 	})
 }
 
-// validateToken validates token signature and checks claims.
-func validateToken(tokenString, currentPassword string) bool {
+// requestToken returns the JWT token of the request from the "token" cookie
+// or the Authorization header; it is empty if there is none.
+func requestToken(r *http.Request) string {
+	if cookie, err := r.Cookie("token"); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+	authHeader := r.Header.Get("Authorization")
+	if strings.HasPrefix(authHeader, "Bearer ") {
+		return strings.TrimPrefix(authHeader, "Bearer ")
+	}
+	return ""
+}
+
+// claimsKey is the request context key of the token claims set by AuthMiddleware.
+type claimsKey struct{}
+
+// Actors recorded for requests without a user name.
+const (
+	// anonymousActor is used when authentication is disabled.
+	anonymousActor = "anonymous"
+	// defaultActor is used for tokens issued without a name.
+	defaultActor = "user"
+)
+
+// maxActorLength limits the user name given at sign-in.
+const maxActorLength = 128
+
+// requestActor returns the actor of the request: the name in the token claims
+// extracted by AuthMiddleware. The name is self-declared at sign-in, not authenticated.
+func requestActor(r *http.Request) string {
+	claims, ok := r.Context().Value(claimsKey{}).(*Claims)
+	if !ok {
+		return anonymousActor
+	}
+	if claims.Name == "" {
+		return defaultActor
+	}
+	return claims.Name
+}
+
+// validateToken validates token signature and checks claims; the claims are returned if the token is valid.
+func validateToken(tokenString, currentPassword string) (*Claims, bool) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("неожиданный метод подписи: %v", token.Header["alg"])
@@ -119,14 +199,14 @@ func validateToken(tokenString, currentPassword string) bool {
 	})
 
 	if err != nil || !token.Valid {
-		return false
+		return nil, false
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok {
-		return claims.PasswordHash == getPasswordHash(currentPassword)
+	if claims, ok := token.Claims.(*Claims); ok && claims.PasswordHash == getPasswordHash(currentPassword) {
+		return claims, true
 	}
 
-	return false
+	return nil, false
 }
 
 // getPasswordHash returns a hash representation stored in JWT claims.
@@ -139,9 +219,12 @@ func getPasswordHash(password string) string {
 }
 
 // SigninHandler authenticates user by password and returns a JWT token.
+// The optional name is recorded as the actor in the audit log ("user" if empty).
+// The actor is self-declared, not authenticated: anyone knowing the password
+// can sign in under any name.
 //
 // Request:  POST /api/signin
-// Body:     {"password": "..."}
+// Body:     {"password": "...", "name": "..."}
 // Response: {"token": "..."}
 func SigninHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -151,6 +234,7 @@ func SigninHandler(w http.ResponseWriter, r *http.Request) {
 
 	var creds struct {
 		Password string `json:"password"`
+		Name     string `json:"name"`
 	}
 	err := json.NewDecoder(r.Body).Decode(&creds)
 	if err != nil {
@@ -158,6 +242,12 @@ func SigninHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	creds.Name = strings.TrimSpace(creds.Name)
+	if utf8.RuneCountInString(creds.Name) > maxActorLength {
+		respondWithJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Имя длиннее %d символов", maxActorLength)})
+		return
+	}
+
 	config := GetConfig()
 	password := config.TodoPassword
 
@@ -173,6 +263,7 @@ func SigninHandler(w http.ResponseWriter, r *http.Request) {
 
 	claims := &Claims{
 		PasswordHash: getPasswordHash(password),
+		Name:         creds.Name,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(8 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
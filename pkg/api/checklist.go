@@ -0,0 +1,222 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+)
+
+// maxItemTitleLength limits the length of a checklist item title in characters.
+const maxItemTitleLength = 256
+
+// ChecklistResp is a response wrapper for GET /api/task/checklist.
+type ChecklistResp struct {
+	Items []*db.ChecklistItem `json:"items"`
+}
+
+// checklistHandler is a multiplexer for checklist items of a task.
+//
+// Methods:
+//   - GET    /api/task/checklist?task_id=<id>  items of the task in their order
+//   - POST   /api/task/checklist               append, body: {"task_id": "...", "title": "...", "required": true}
+//   - PUT    /api/task/checklist               update, body: {"id": "...", "title": "...", "done": true, "required": false}
+//   - DELETE /api/task/checklist?id=<id>       delete
+func checklistHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		listChecklistHandler(w, r)
+	case http.MethodPost:
+		addItemHandler(w, r)
+	case http.MethodPut:
+		updateItemHandler(w, r)
+	case http.MethodDelete:
+		deleteItemHandler(w, r)
+	default:
+		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Метод не поддерживается"})
+	}
+}
+
+// listChecklistHandler returns the checklist of a task.
+func listChecklistHandler(w http.ResponseWriter, r *http.Request) {
+	taskID := r.URL.Query().Get("task_id")
+	if taskID == "" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан идентификатор задачи"})
+		return
+	}
+	if _, err := store.Get(taskID); err != nil {
+		writeJson(w, http.StatusNotFound, map[string]string{"error": "Задача не найдена"})
+		return
+	}
+
+	items, err := store.Checklist(taskID)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, ChecklistResp{Items: items})
+}
+
+// decodeItem reads a checklist item from the request body and validates its title.
+func decodeItem(r *http.Request) (*db.ChecklistItem, error) {
+	var item db.ChecklistItem
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		return nil, fmt.Errorf("Ошибка десериализации JSON: %v", err)
+	}
+	if err := checkItem(&item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// addItemHandler appends an item to a task checklist.
+func addItemHandler(w http.ResponseWriter, r *http.Request) {
+	item, err := decodeItem(r)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	task, err := store.Get(item.TaskID)
+	if err != nil {
+		writeJson(w, http.StatusNotFound, map[string]string{"error": "Задача не найдена"})
+		return
+	}
+	item.TaskID = task.ID
+
+	id, err := storeFor(r).AddItem(item)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Ошибка сохранения пункта: " + err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]string{"id": strconv.FormatInt(id, 10)})
+}
+
+// updateItemHandler updates a checklist item.
+func updateItemHandler(w http.ResponseWriter, r *http.Request) {
+	item, err := decodeItem(r)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if item.ID == "" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан идентификатор"})
+		return
+	}
+	writeItemResult(w, storeFor(r).UpdateItem(item), "Ошибка обновления пункта: ")
+}
+
+// deleteItemHandler deletes a checklist item by ID.
+func deleteItemHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан идентификатор"})
+		return
+	}
+	writeItemResult(w, storeFor(r).DeleteItem(id), "Ошибка удаления пункта: ")
+}
+
+// checklistToggleHandler flips the done flag of a checklist item and returns the item.
+//
+// Method: POST /api/task/checklist/toggle?id=<id>
+func checklistToggleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Метод не поддерживается"})
+		return
+	}
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан идентификатор"})
+		return
+	}
+	item, err := store.GetItem(id)
+	if err != nil {
+		writeItemResult(w, err, "")
+		return
+	}
+
+	item.Done = !item.Done
+	if err := storeFor(r).UpdateItem(item); err != nil {
+		writeItemResult(w, err, "Ошибка обновления пункта: ")
+		return
+	}
+	writeJson(w, http.StatusOK, item)
+}
+
+// checklistReorderHandler sets the order of checklist items.
+//
+// Method: POST /api/task/checklist/reorder
+// Body:   {"task_id": "...", "ids": ["3", "1", "2"]} — all item IDs of the task in the new order.
+func checklistReorderHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Метод не поддерживается"})
+		return
+	}
+	var req struct {
+		TaskID string   `json:"task_id"`
+		IDs    []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Ошибка десериализации JSON: " + err.Error()})
+		return
+	}
+	task, err := store.Get(req.TaskID)
+	if err != nil {
+		writeJson(w, http.StatusNotFound, map[string]string{"error": "Задача не найдена"})
+		return
+	}
+	seen := map[string]bool{}
+	for _, id := range req.IDs {
+		if seen[id] {
+			writeJson(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("пункт %s указан дважды", id)})
+			return
+		}
+		seen[id] = true
+	}
+
+	err = storeFor(r).ReorderChecklist(task.ID, req.IDs)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, struct{}{})
+}
+
+// writeItemResult writes the result of a checklist item update or deletion.
+func writeItemResult(w http.ResponseWriter, err error, prefix string) {
+	switch {
+	case err == nil:
+		writeJson(w, http.StatusOK, struct{}{})
+	case err.Error() == "пункт не найден":
+		writeJson(w, http.StatusNotFound, map[string]string{"error": "Пункт не найден"})
+	default:
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": prefix + err.Error()})
+	}
+}
+
+// checkItem trims and validates the title of a checklist item.
+func checkItem(item *db.ChecklistItem) error {
+	item.Title = strings.TrimSpace(item.Title)
+	if item.Title == "" {
+		return errors.New("Не указан текст пункта")
+	}
+	if utf8.RuneCountInString(item.Title) > maxItemTitleLength {
+		return fmt.Errorf("текст пункта длиннее %d символов", maxItemTitleLength)
+	}
+	return nil
+}
+
+// openRequiredItems returns the titles of required checklist items of a task that are not done.
+func openRequiredItems(task *db.Task) []string {
+	var open []string
+	for _, item := range task.Checklist {
+		if item.Required && !item.Done {
+			open = append(open, item.Title)
+		}
+	}
+	return open
+}
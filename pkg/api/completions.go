@@ -0,0 +1,83 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+)
+
+// CompletionsResp is a response wrapper for GET /api/task/history and GET /api/completions.
+type CompletionsResp struct {
+	Completions []*db.Completion `json:"completions"`
+}
+
+// taskHistoryHandler returns the completions of a task, the latest first.
+// The history stays available after a non-repeating task is done and deleted.
+//
+// Method: GET /api/task/history?id=<id>
+func taskHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Метод не поддерживается"})
+		return
+	}
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан идентификатор"})
+		return
+	}
+
+	completions, err := store.History(id)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, CompletionsResp{Completions: completions})
+}
+
+// completionsHandler returns the completions of all tasks, the latest first.
+// from and to (YYYYMMDD, both optional and inclusive) are days in the configured time zone.
+//
+// Method: GET /api/completions?from=YYYYMMDD&to=YYYYMMDD
+func completionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Метод не поддерживается"})
+		return
+	}
+	from, to, err := dayRange(r)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	completions, err := store.Completions(from, to)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, CompletionsResp{Completions: completions})
+}
+
+// dayRange reads the from and to query parameters (YYYYMMDD, both optional and inclusive)
+// as days in the configured time zone and returns the range [from, to); an omitted bound is zero.
+func dayRange(r *http.Request) (time.Time, time.Time, error) {
+	loc := GetConfig().Location()
+	var bounds [2]time.Time
+	for i, name := range []string{"from", "to"} {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			continue
+		}
+		day, err := time.ParseInLocation(DateFormat, value, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("неверный параметр %s: %v", name, err)
+		}
+		bounds[i] = day
+	}
+	from, to := bounds[0], bounds[1]
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+	return from, to, nil
+}
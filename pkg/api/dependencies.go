@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// DependenciesResp is a response wrapper for GET /api/task/dependencies.
+type DependenciesResp struct {
+	BlockedBy []string `json:"blocked_by"`
+	Blocks    []string `json:"blocks"`
+}
+
+// dependenciesHandler is a multiplexer for task dependencies.
+// A blocked task cannot be done until all its blockers are done.
+//
+// Methods:
+//   - GET    /api/task/dependencies?task_id=<id>                   blockers of the task and tasks it blocks
+//   - POST   /api/task/dependencies                                link, body: {"task_id": "...", "blocker_id": "..."}
+//   - DELETE /api/task/dependencies?task_id=<id>&blocker_id=<id>   unlink
+func dependenciesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		listDependenciesHandler(w, r)
+	case http.MethodPost:
+		linkHandler(w, r)
+	case http.MethodDelete:
+		unlinkHandler(w, r)
+	default:
+		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Метод не поддерживается"})
+	}
+}
+
+// listDependenciesHandler returns the blockers and dependents of a task.
+func listDependenciesHandler(w http.ResponseWriter, r *http.Request) {
+	taskID := r.URL.Query().Get("task_id")
+	if taskID == "" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан идентификатор задачи"})
+		return
+	}
+	task, err := store.Get(taskID)
+	if err != nil {
+		writeJson(w, http.StatusNotFound, map[string]string{"error": "Задача не найдена"})
+		return
+	}
+
+	blocks, err := store.Dependents(task.ID)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	blockedBy := task.BlockedBy
+	if blockedBy == nil {
+		blockedBy = []string{}
+	}
+	writeJson(w, http.StatusOK, DependenciesResp{BlockedBy: blockedBy, Blocks: blocks})
+}
+
+// linkHandler makes a task depend on a blocker.
+func linkHandler(w http.ResponseWriter, r *http.Request) {
+	var dep struct {
+		TaskID    string `json:"task_id"`
+		BlockerID string `json:"blocker_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&dep); err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Ошибка десериализации JSON: " + err.Error()})
+		return
+	}
+	if dep.TaskID == "" || dep.BlockerID == "" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан идентификатор задачи"})
+		return
+	}
+
+	err := storeFor(r).Link(dep.TaskID, dep.BlockerID)
+	if err != nil {
+		switch err.Error() {
+		case "задача не найдена":
+			writeJson(w, http.StatusNotFound, map[string]string{"error": "Задача не найдена"})
+		case "цикл зависимостей":
+			writeJson(w, http.StatusConflict, map[string]string{"error": "Зависимость образует цикл"})
+		default:
+			writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Ошибка сохранения зависимости: " + err.Error()})
+		}
+		return
+	}
+	writeJson(w, http.StatusOK, struct{}{})
+}
+
+// unlinkHandler removes a dependency between two tasks.
+func unlinkHandler(w http.ResponseWriter, r *http.Request) {
+	taskID, blockerID := r.URL.Query().Get("task_id"), r.URL.Query().Get("blocker_id")
+	if taskID == "" || blockerID == "" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан идентификатор задачи"})
+		return
+	}
+
+	err := storeFor(r).Unlink(taskID, blockerID)
+	if err != nil {
+		if err.Error() == "зависимость не найдена" {
+			writeJson(w, http.StatusNotFound, map[string]string{"error": "Зависимость не найдена"})
+		} else {
+			writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Ошибка удаления зависимости: " + err.Error()})
+		}
+		return
+	}
+	writeJson(w, http.StatusOK, struct{}{})
+}
@@ -0,0 +1,351 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+)
+
+// maxHolidayEventDays limits how many days a single iCalendar event may expand to,
+// with all its recurrences.
+const maxHolidayEventDays = 366
+
+// holidayRecurrenceYears is how far ahead recurring iCalendar events are expanded:
+// until the end of the year that many years from now.
+const holidayRecurrenceYears = 10
+
+// HolidaysResp is a response wrapper for GET /api/holidays.
+type HolidaysResp struct {
+	Holidays []*db.Holiday `json:"holidays"`
+}
+
+// holidaysHandler is a multiplexer for the holiday calendar.
+//
+// Methods:
+//   - GET    /api/holidays?from=YYYYMMDD&to=YYYYMMDD  list (both bounds optional)
+//   - POST   /api/holidays                            create, body: {"date": "...", "title": "..."}
+//   - PUT    /api/holidays                            update, body: {"id": "...", "date": "...", "title": "..."}
+//   - DELETE /api/holidays?id=<id>                    delete
+func holidaysHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		listHolidaysHandler(w, r)
+	case http.MethodPost:
+		addHolidayHandler(w, r)
+	case http.MethodPut:
+		updateHolidayHandler(w, r)
+	case http.MethodDelete:
+		deleteHolidayHandler(w, r)
+	default:
+		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Метод не поддерживается"})
+	}
+}
+
+// listHolidaysHandler returns holidays in the optional [from, to] range.
+func listHolidaysHandler(w http.ResponseWriter, r *http.Request) {
+	from := r.URL.Query().Get("from")
+	to := r.URL.Query().Get("to")
+	for name, value := range map[string]string{"from": from, "to": to} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(DateFormat, value); err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("неверный параметр %s: %v", name, err)})
+			return
+		}
+	}
+
+	holidays, err := storeFor(r).Holidays(from, to)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, HolidaysResp{Holidays: holidays})
+}
+
+// decodeHoliday reads and validates a holiday from the request body.
+func decodeHoliday(r *http.Request) (*db.Holiday, error) {
+	var h db.Holiday
+	if err := json.NewDecoder(r.Body).Decode(&h); err != nil {
+		return nil, fmt.Errorf("Ошибка десериализации JSON: %v", err)
+	}
+	if _, err := time.Parse(DateFormat, h.Date); err != nil {
+		return nil, fmt.Errorf("некорректная дата: %v", err)
+	}
+	return &h, nil
+}
+
+// addHolidayHandler creates a holiday.
+func addHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	h, err := decodeHoliday(r)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	id, err := storeFor(r).AddHoliday(h)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Ошибка сохранения праздника: " + err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]string{"id": strconv.FormatInt(id, 10)})
+}
+
+// updateHolidayHandler updates a holiday.
+func updateHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	h, err := decodeHoliday(r)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if h.ID == "" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан идентификатор"})
+		return
+	}
+
+	err = storeFor(r).UpdateHoliday(h)
+	if err != nil {
+		if err.Error() == "праздник не найден" {
+			writeJson(w, http.StatusNotFound, map[string]string{"error": "Праздник не найден"})
+		} else {
+			writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Ошибка обновления праздника: " + err.Error()})
+		}
+		return
+	}
+	writeJson(w, http.StatusOK, struct{}{})
+}
+
+// deleteHolidayHandler deletes a holiday by ID.
+func deleteHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан идентификатор"})
+		return
+	}
+
+	err := storeFor(r).DeleteHoliday(id)
+	if err != nil {
+		if err.Error() == "праздник не найден" {
+			writeJson(w, http.StatusNotFound, map[string]string{"error": "Праздник не найден"})
+		} else {
+			writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Ошибка удаления праздника: " + err.Error()})
+		}
+		return
+	}
+	writeJson(w, http.StatusOK, struct{}{})
+}
+
+// importHolidaysHandler imports holidays from an iCalendar (.ics) file.
+//
+// Method: POST /api/holidays/import
+// Body:   text/calendar; every all-day VEVENT becomes one holiday per day,
+// recurring events (RRULE, RDATE, EXDATE) become one per day of every recurrence
+// Result: {"imported": N}
+func importHolidaysHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Метод не поддерживается"})
+		return
+	}
+
+	holidays, err := parseICalHolidays(r.Body)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Ошибка разбора iCalendar: " + err.Error()})
+		return
+	}
+	if err := storeFor(r).ImportHolidays(holidays); err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Ошибка сохранения праздников: " + err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]int{"imported": len(holidays)})
+}
+
+// icalEvent holds the properties of a VEVENT used for holidays; the line numbers
+// of RRULE, RDATE and EXDATE are kept for error messages.
+type icalEvent struct {
+	start, end, summary string
+	rrule               string
+	rruleLine           int
+	rdates, exdates     []icalValue
+}
+
+// icalValue is a property value with the number of its line.
+type icalValue struct {
+	value string
+	line  int
+}
+
+// parseICalHolidays extracts holidays from VEVENT components of an iCalendar stream.
+//
+// DTSTART/DTEND may be dates or date-times; only the date part is used.
+// DTEND is exclusive as in RFC 5545; without it the event lasts one day.
+// Recurring events are expanded with the RRULE engine of the repeat rules
+// until the end of the year holidayRecurrenceYears from now; RDATE adds dates
+// and EXDATE removes them. An RRULE the engine does not support is an error.
+func parseICalHolidays(r io.Reader) ([]*db.Holiday, error) {
+	lines, err := unfoldICal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	horizon := time.Date(time.Now().Year()+holidayRecurrenceYears, time.December, 31, 0, 0, 0, 0, time.UTC)
+	var holidays []*db.Holiday
+	var inEvent bool
+	var event icalEvent
+
+	for i, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		// Property parameters (";VALUE=DATE") are not needed.
+		name, _, _ = strings.Cut(strings.ToUpper(name), ";")
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			inEvent = true
+			event = icalEvent{}
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if !inEvent {
+				continue
+			}
+			inEvent = false
+			days, err := expandICalEvent(event, horizon, i+1)
+			if err != nil {
+				return nil, err
+			}
+			for _, day := range days {
+				holidays = append(holidays, &db.Holiday{Date: day, Title: event.summary})
+			}
+		case inEvent && name == "DTSTART":
+			event.start = value
+		case inEvent && name == "DTEND":
+			event.end = value
+		case inEvent && name == "SUMMARY":
+			event.summary = unescapeICal(value)
+		case inEvent && name == "RRULE":
+			if event.rrule != "" {
+				return nil, fmt.Errorf("строка %d: больше одного RRULE в событии", i+1)
+			}
+			event.rrule, event.rruleLine = value, i+1
+		case inEvent && name == "RDATE":
+			event.rdates = append(event.rdates, icalValue{value, i + 1})
+		case inEvent && name == "EXDATE":
+			event.exdates = append(event.exdates, icalValue{value, i + 1})
+		}
+	}
+
+	if len(holidays) == 0 {
+		return nil, fmt.Errorf("не найдено ни одного события VEVENT")
+	}
+	return holidays, nil
+}
+
+// expandICalEvent returns all dates of an event: from DTSTART to DTEND (exclusive)
+// for its first occurrence and for every recurrence up to horizon.
+// end is the number of the END:VEVENT line.
+func expandICalEvent(event icalEvent, horizon time.Time, end int) ([]string, error) {
+	from, err := icalDate(event.start)
+	if err != nil {
+		return nil, fmt.Errorf("строка %d: некорректный DTSTART: %q", end, event.start)
+	}
+	length := 1
+	if event.end != "" {
+		to, err := icalDate(event.end)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: некорректный DTEND: %q", end, event.end)
+		}
+		if to.After(from) {
+			length = int(to.Sub(from).Hours() / 24)
+		}
+	}
+	if length > maxHolidayEventDays {
+		return nil, fmt.Errorf("строка %d: событие длиннее %d дней", end, maxHolidayEventDays)
+	}
+
+	starts := []string{from.Format(DateFormat)}
+	if event.rrule != "" {
+		if _, err := parseRRule(event.rrule); err != nil {
+			return nil, fmt.Errorf("строка %d: некорректный RRULE: %v", event.rruleLine, err)
+		}
+		dates, err := Occurrences(from.Format(DateFormat), event.rrule, from, horizon, maxHolidayEventDays, nil)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: некорректный RRULE: %v", event.rruleLine, err)
+		}
+		starts = append(starts, dates...)
+	}
+	for _, rdate := range event.rdates {
+		for _, value := range strings.Split(rdate.value, ",") {
+			date, err := icalDate(value)
+			if err != nil || strings.Contains(value, "/") {
+				return nil, fmt.Errorf("строка %d: некорректный RDATE: %q", rdate.line, value)
+			}
+			starts = append(starts, date.Format(DateFormat))
+		}
+	}
+	excluded := map[string]bool{}
+	for _, exdate := range event.exdates {
+		for _, value := range strings.Split(exdate.value, ",") {
+			date, err := icalDate(value)
+			if err != nil {
+				return nil, fmt.Errorf("строка %d: некорректный EXDATE: %q", exdate.line, value)
+			}
+			excluded[date.Format(DateFormat)] = true
+		}
+	}
+
+	seen := map[string]bool{}
+	var days []string
+	for _, start := range starts {
+		if excluded[start] {
+			continue
+		}
+		d, _ := time.Parse(DateFormat, start)
+		for i := 0; i < length; i++ {
+			day := d.AddDate(0, 0, i).Format(DateFormat)
+			if seen[day] {
+				continue
+			}
+			if len(days) == maxHolidayEventDays {
+				return nil, fmt.Errorf("строка %d: событие с повторениями длиннее %d дней", end, maxHolidayEventDays)
+			}
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+	return days, nil
+}
+
+// icalDate parses the date part of a DATE or DATE-TIME value.
+func icalDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("некорректная дата: %q", value)
+	}
+	return time.Parse(DateFormat, value[:8])
+}
+
+// unfoldICal reads content lines joining folded continuation lines (RFC 5545, 3.1).
+func unfoldICal(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// unescapeICal decodes TEXT value escapes (\\, \;, \, and \n).
+func unescapeICal(value string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, " ", `\N`, " ").Replace(value)
+}
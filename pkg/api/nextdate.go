@@ -1,174 +1,177 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
-	"strings"
 	"time"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
 )
 
 // DateFormat is the canonical date format used by the API and database.
 // It corresponds to YYYYMMDD.
 const DateFormat = "20060102"
 
+// ErrSeriesEnded is returned by NextDate when the rule has no more occurrences
+// (RRULE COUNT/UNTIL is exhausted or the rule can never match again).
+var ErrSeriesEnded = errors.New("у правила нет следующих повторений")
+
+// ErrSeriesTooLong is returned when reaching a date takes more than maxSeriesSteps
+// occurrences or maxSeriesYears years from the start of the series (e.g. "b 1" from year 1).
+// The "d", "w", "m" and "y" rules jump over the past in one step and never hit the caps
+// for a start date in the past (see series.jump).
+var ErrSeriesTooLong = fmt.Errorf("дата слишком далеко от начала повторений (больше %d повторений или %d лет)",
+	maxSeriesSteps, maxSeriesYears)
+
+// Hard caps on stepping through a series per call, so that a start date far from
+// the requested dates cannot make a request loop for long.
+const (
+	// maxSeriesSteps is about 55 years of a daily rule.
+	maxSeriesSteps = 20000
+	// maxSeriesYears bounds the span scanned by sparse rules.
+	maxSeriesYears = 500
+)
+
+// lastDate is the last date that can be written in DateFormat.
+var lastDate = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
 // NextDate calculates the next occurrence date based on the repeat rule.
 //
 // Parameters:
-//   - now:    reference point (usually time.Now())
-//   - dstart: start date in DateFormat (YYYYMMDD)
-//   - repeat: repeat rule string, e.g. "d 1", "w 1,3,5", "m 1,15 1,6", "y"
+//   - now:      reference point (usually time.Now())
+//   - dstart:   start date in DateFormat (YYYYMMDD)
+//   - repeat:   repeat rule string, e.g. "d 1", "b 5", "w 1,3,5", "m 1,15 1,6", "y"
+//     or an RRULE such as "FREQ=MONTHLY;BYDAY=-1FR" (see ParseRepeat)
+//   - holidays: calendar used by "b" rules (nil means no holidays)
 //
-// Returns the next date in DateFormat.
-func NextDate(now time.Time, dstart string, repeat string) (string, error) {
+// Returns the first occurrence strictly after both dstart and now, in DateFormat.
+// For RRULE, dstart is the first occurrence of the series and counts towards COUNT.
+func NextDate(now time.Time, dstart string, repeat string, holidays db.HolidayStore) (string, error) {
+	s, err := newSeries(dstart, repeat, holidays)
+	if err != nil {
+		return "", err
+	}
+	if err := s.skipTo(now); err != nil {
+		return "", err
+	}
+	return s.date.Format(DateFormat), nil
+}
+
+// series steps through the occurrences of a repeat rule from its start date.
+type series struct {
+	repeat string
+	rule   RepeatRule
+	limit  time.Time
+	// date is the current occurrence and occurrence its number (the start date is the first one).
+	date       time.Time
+	occurrence int
+	steps      int
+}
+
+// newSeries parses the start date and the rule (loading the holidays for "b") once.
+func newSeries(dstart, repeat string, holidays db.HolidayStore) (*series, error) {
 	if repeat == "" {
-		return "", fmt.Errorf("правило повторения не должно быть пустым")
+		return nil, fmt.Errorf("правило повторения не должно быть пустым")
 	}
 
 	date, err := time.Parse(DateFormat, dstart)
 	if err != nil {
-		return "", fmt.Errorf("некорректная дата начала: %v", err)
+		return nil, fmt.Errorf("некорректная дата начала: %v", err)
 	}
 
-	parts := strings.Split(repeat, " ")
-	switch parts[0] {
-
-	case "d":
-		if len(parts) < 2 {
-			return "", fmt.Errorf("отсутствует параметр для правила d")
-		}
-		days, err := strconv.Atoi(parts[1])
+	rule, err := ParseRepeat(repeat)
+	if err != nil {
+		return nil, err
+	}
+	if rule.Unit == RepeatBusiness {
+		rule.Holidays, err = holidayDates(holidays)
 		if err != nil {
-			return "", fmt.Errorf("неверный параметр для d: %v", err)
-		}
-		if days <= 0 || days > 400 {
-			return "", fmt.Errorf("число дней для d должно быть от 1 до 400")
-		}
-		for {
-			date = date.AddDate(0, 0, days)
-			if afterNow(date, now) {
-				break
-			}
+			return nil, err
 		}
-		return date.Format(DateFormat), nil
-
-	case "y":
-		for {
-			date = date.AddDate(1, 0, 0)
-			if afterNow(date, now) {
-				break
-			}
-		}
-		return date.Format(DateFormat), nil
+	}
+	limit := date.AddDate(maxSeriesYears, 0, 0)
+	return &series{repeat: repeat, rule: rule, limit: limit, date: date, occurrence: 1}, nil
+}
 
-	case "w":
-		if len(parts) < 2 {
-			return "", fmt.Errorf("отсутствует список дней недели")
-		}
-		weekStrs := strings.Split(parts[1], ",")
-		var weekdays [8]bool
-		for _, w := range weekStrs {
-			dayNum, err := strconv.Atoi(w)
-			if err != nil || dayNum < 1 || dayNum > 7 {
-				return "", fmt.Errorf("некорректный день недели: %v", w)
-			}
-			weekdays[dayNum] = true
-		}
-		for {
-			date = date.AddDate(0, 0, 1)
-			weekday := int(date.Weekday())
-			if weekday == 0 {
-				weekday = 7
-			}
-			if weekdays[weekday] && date.After(now) {
-				break
-			}
-		}
-		return date.Format(DateFormat), nil
+// next moves to the following occurrence. It returns ErrSeriesEnded if there is none
+// (before the end of year 9999) and ErrSeriesTooLong once the series has been stepped
+// too far (see maxSeriesSteps).
+func (s *series) next() error {
+	if s.steps >= maxSeriesSteps || s.date.After(s.limit) {
+		return ErrSeriesTooLong
+	}
+	s.steps++
+	next, ok := s.rule.Next(s.date)
+	s.occurrence++
+	if !ok || next.After(lastDate) || (s.rule.RRule != nil && s.rule.RRule.Count > 0 && s.occurrence > s.rule.RRule.Count) {
+		return fmt.Errorf("%w: %q", ErrSeriesEnded, s.repeat)
+	}
+	s.date = next
+	return nil
+}
 
-	case "m":
-		if len(parts) < 2 {
-			return "", fmt.Errorf("отсутствует список дней месяца")
+// skipTo moves to the first occurrence strictly after now (by date).
+func (s *series) skipTo(now time.Time) error {
+	s.jump(now)
+	for {
+		if err := s.next(); err != nil {
+			return err
 		}
-		daysStr := strings.Split(parts[1], ",")
-		var dayFlags [32]bool
-		hasMinus1 := false
-		hasMinus2 := false
-
-		for _, d := range daysStr {
-			dayNum, err := strconv.Atoi(d)
-			if err != nil {
-				return "", fmt.Errorf("некорректный день месяца: %v", err)
-			}
-			if dayNum == -1 {
-				hasMinus1 = true
-			} else if dayNum == -2 {
-				hasMinus2 = true
-			} else if dayNum >= 1 && dayNum <= 31 {
-				dayFlags[dayNum] = true
-			} else {
-				return "", fmt.Errorf("день месяца вне допустимого диапазона: %d", dayNum)
-			}
+		if afterNow(s.date, now) {
+			return nil
 		}
+	}
+}
 
-		var monthFlags [13]bool
-		if len(parts) == 3 {
-			monthStrs := strings.Split(parts[2], ",")
-			for _, m := range monthStrs {
-				monthNum, err := strconv.Atoi(m)
-				if err != nil || monthNum < 1 || monthNum > 12 {
-					return "", fmt.Errorf("месяц вне допустимого диапазона: %v", m)
-				}
-				monthFlags[monthNum] = true
-			}
-		} else {
-			for i := 1; i <= 12; i++ {
-				monthFlags[i] = true
-			}
-		}
+// jump moves the series forward to a date not after now without stepping through
+// every occurrence, for the rules whose dates can be computed directly:
+//   - d and w repeat with a fixed period (N days, N weeks), so the date moves by whole periods;
+//   - m does not depend on the start date, so the date moves to now itself;
+//   - y moves by whole years (a start on 29 February continues on 1 March, as with stepping).
+//
+// The dates skipped are all on or before now. "b" and RRULE keep stepping
+// (COUNT needs the number of each occurrence). The limit is moved along with the date.
+func (s *series) jump(now time.Time) {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	if !today.After(s.date) {
+		return
+	}
 
-		for {
-			date = date.AddDate(0, 0, 1)
-			day := date.Day()
-			month := int(date.Month())
-			year := date.Year()
-
-			if !monthFlags[month] {
-				continue
-			}
-
-			isMatch := false
-			if dayFlags[day] {
-				isMatch = true
-			}
-			if hasMinus1 {
-				lastDay := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
-				if day == lastDay {
-					isMatch = true
-				}
-			}
-			if hasMinus2 {
-				lastDay := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
-				if day == lastDay-1 {
-					isMatch = true
-				}
-			}
-
-			if isMatch && date.After(now) {
-				break
-			}
+	switch s.rule.Unit {
+	case RepeatDaily, RepeatWeekly:
+		period := int64(s.rule.Interval)
+		if s.rule.Unit == RepeatWeekly {
+			period *= 7
+		}
+		days := (today.Unix() - s.date.Unix()) / (24 * 60 * 60)
+		s.date = s.date.AddDate(0, 0, int(days/period*period))
+	case RepeatMonthly:
+		s.date = today
+	case RepeatYearly:
+		years := today.Year() - s.date.Year() - 1
+		if years < 1 {
+			return
+		}
+		leapDay := s.date.Month() == time.February && s.date.Day() == 29
+		s.date = s.date.AddDate(years, 0, 0)
+		if leapDay && s.date.Day() == 29 {
+			s.date = s.date.AddDate(0, 0, 1)
 		}
-		return date.Format(DateFormat), nil
-
 	default:
-		return "", fmt.Errorf("неподдерживаемый формат правила повторения: %s", parts[0])
+		return
 	}
+	s.limit = s.date.AddDate(maxSeriesYears, 0, 0)
 }
 
 // nextDayHandler implements a simple endpoint that returns the next date as plain text.
 //
-// Method: GET /api/nextdate?now=YYYYMMDD&date=YYYYMMDD&repeat=<rule>
+// Method: GET /api/nextdate?now=YYYYMMDD&date=YYYYMMDD&repeat=<rule>&roll=<policy>&tz=<zone>
 // The "now" parameter is optional (defaults to current time).
+// The "tz" parameter is optional: the IANA zone used for "today" when "now" is omitted (defaults to TODO_TZ).
+// The "roll" parameter is optional: "forward" or "backward" moves the result off weekends and holidays.
+// RRULE values must be URL-encoded since they contain ';' and '='.
 func nextDayHandler(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method != http.MethodGet {
@@ -180,10 +183,20 @@ func nextDayHandler(w http.ResponseWriter, r *http.Request) {
 	dstart := r.FormValue("date")
 	repeat := r.FormValue("repeat")
 
+	loc := GetConfig().Location()
+	if tz := r.FormValue("tz"); tz != "" {
+		var err error
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("неверный параметр tz: %v", err)})
+			return
+		}
+	}
+
 	var now time.Time
 	var err error
 	if nowStr == "" {
-		now = time.Now()
+		now = time.Now().In(loc)
 	} else {
 		now, err = time.Parse(DateFormat, nowStr)
 		if err != nil {
@@ -192,7 +205,10 @@ func nextDayHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	next, err := NextDate(now, dstart, repeat)
+	next, err := NextDate(now, dstart, repeat, storeFor(r))
+	if err == nil {
+		next, err = RollDate(now, next, r.FormValue("roll"), storeFor(r))
+	}
 	if err != nil {
 		writeJson(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("ошибка вычисления следующей даты: %v", err)})
 		return
@@ -204,11 +220,11 @@ func nextDayHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // afterNow compares dates ignoring time-of-day.
-// It returns true if date >= now (by date) in UTC.
+// It returns true if date > now (by date) in UTC.
 func afterNow(date, now time.Time) bool {
 	y1, m1, d1 := date.Date()
 	y2, m2, d2 := now.Date()
 	dateZero := time.Date(y1, m1, d1, 0, 0, 0, 0, time.UTC)
 	nowZero := time.Date(y2, m2, d2, 0, 0, 0, 0, time.UTC)
-	return !dateZero.Before(nowZero)
+	return dateZero.After(nowZero)
 }
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+)
+
+const (
+	// defaultOccurrences is the number of dates returned when "limit" is not set.
+	defaultOccurrences = 10
+	// maxOccurrences is the hard cap on the number of dates returned per request.
+	maxOccurrences = 500
+)
+
+// Occurrences returns up to limit occurrences of the repeat rule for a series starting at dstart.
+//
+// Only dates in [from, to] are returned; a zero "to" means no upper bound.
+// The start date itself is not included, as with NextDate.
+// Iteration stops early when the series ends (RRULE COUNT/UNTIL).
+// A "from" too far from dstart returns ErrSeriesTooLong (see maxSeriesSteps).
+func Occurrences(dstart, repeat string, from, to time.Time, limit int, holidays db.HolidayStore) ([]string, error) {
+	s, err := newSeries(dstart, repeat, holidays)
+	if err != nil {
+		return nil, err
+	}
+
+	dates := []string{}
+	// skipTo returns dates strictly after "now", so start one day before "from".
+	err = s.skipTo(from.AddDate(0, 0, -1))
+	for err == nil {
+		if !to.IsZero() && s.date.After(to) {
+			break
+		}
+		dates = append(dates, s.date.Format(DateFormat))
+		if len(dates) == limit {
+			break
+		}
+		err = s.next()
+	}
+	if err != nil && !errors.Is(err, ErrSeriesEnded) {
+		return nil, err
+	}
+	return dates, nil
+}
+
+// occurrencesHandler returns upcoming occurrences of a repeat rule as a JSON array of dates.
+//
+// Method: GET /api/occurrences?date=YYYYMMDD&repeat=<rule>&from=YYYYMMDD&to=YYYYMMDD&limit=N
+//   - date, repeat: series start and repeat rule (as in /api/nextdate)
+//   - from (optional): first date to include, defaults to the start date
+//   - to (optional):   last date to include
+//   - limit (optional): number of dates, 10 by default, at most 500
+func occurrencesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Метод не поддерживается"})
+		return
+	}
+
+	dstart := r.FormValue("date")
+	repeat := r.FormValue("repeat")
+
+	from, err := time.Parse(DateFormat, dstart)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("неверный параметр date: %v", err)})
+		return
+	}
+	if fromStr := r.FormValue("from"); fromStr != "" {
+		from, err = time.Parse(DateFormat, fromStr)
+		if err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("неверный параметр from: %v", err)})
+			return
+		}
+	}
+
+	var to time.Time
+	if toStr := r.FormValue("to"); toStr != "" {
+		to, err = time.Parse(DateFormat, toStr)
+		if err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("неверный параметр to: %v", err)})
+			return
+		}
+	}
+
+	limit := defaultOccurrences
+	if limitStr := r.FormValue("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxOccurrences {
+			writeJson(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("параметр limit должен быть числом от 1 до %d", maxOccurrences)})
+			return
+		}
+	}
+
+	dates, err := Occurrences(dstart, repeat, from, to, limit, storeFor(r))
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("ошибка вычисления повторений: %v", err)})
+		return
+	}
+	writeJson(w, http.StatusOK, dates)
+}
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+)
+
+// maxProjectNameLength limits the length of a project name in characters.
+const maxProjectNameLength = 128
+
+// projectColor matches the "#rrggbb" project color.
+var projectColor = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// ProjectsResp is a response wrapper for GET /api/projects.
+type ProjectsResp struct {
+	Projects []*db.Project `json:"projects"`
+}
+
+// projectsHandler is a multiplexer for projects.
+//
+// Methods:
+//   - GET    /api/projects?archived=true  list (archived projects only with archived=true)
+//   - GET    /api/projects?id=<id>        get one project
+//   - POST   /api/projects                create, body: {"name": "...", "color": "#rrggbb", "archived": false}
+//   - PUT    /api/projects                update, body: {"id": "...", "name": "...", "color": "...", "archived": true}
+//   - DELETE /api/projects?id=<id>&mode=cascade|reassign&to=<id>
+//     delete; with mode=cascade the project tasks are deleted too, otherwise (reassign, default)
+//     they are moved to the project "to" or, if it is empty, left without a project
+func projectsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Get("id") != "" {
+			getProjectHandler(w, r)
+		} else {
+			listProjectsHandler(w, r)
+		}
+	case http.MethodPost:
+		addProjectHandler(w, r)
+	case http.MethodPut:
+		updateProjectHandler(w, r)
+	case http.MethodDelete:
+		deleteProjectHandler(w, r)
+	default:
+		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Метод не поддерживается"})
+	}
+}
+
+// listProjectsHandler returns projects ordered by name.
+func listProjectsHandler(w http.ResponseWriter, r *http.Request) {
+	archived := false
+	if v := r.URL.Query().Get("archived"); v != "" {
+		var err error
+		archived, err = strconv.ParseBool(v)
+		if err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]string{"error": "неверный параметр archived: допустимо true или false"})
+			return
+		}
+	}
+
+	projects, err := store.Projects(archived)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, ProjectsResp{Projects: projects})
+}
+
+// getProjectHandler returns a single project by ID.
+func getProjectHandler(w http.ResponseWriter, r *http.Request) {
+	project, err := findProject(storeFor(r), r.URL.Query().Get("id"))
+	if err != nil {
+		writeJson(w, http.StatusNotFound, map[string]string{"error": "Проект не найден"})
+		return
+	}
+	writeJson(w, http.StatusOK, project)
+}
+
+// decodeProject reads and validates a project from the request body.
+func decodeProject(r *http.Request) (*db.Project, error) {
+	var p db.Project
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		return nil, fmt.Errorf("Ошибка десериализации JSON: %v", err)
+	}
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return nil, errors.New("Не указано название проекта")
+	}
+	if utf8.RuneCountInString(p.Name) > maxProjectNameLength {
+		return nil, fmt.Errorf("название проекта длиннее %d символов", maxProjectNameLength)
+	}
+	p.Color = strings.ToLower(p.Color)
+	if p.Color != "" && !projectColor.MatchString(p.Color) {
+		return nil, fmt.Errorf("некорректный цвет %q: ожидается #rrggbb", p.Color)
+	}
+	return &p, nil
+}
+
+// addProjectHandler creates a project.
+func addProjectHandler(w http.ResponseWriter, r *http.Request) {
+	p, err := decodeProject(r)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	id, err := store.AddProject(p)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Ошибка сохранения проекта: " + err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]string{"id": strconv.FormatInt(id, 10)})
+}
+
+// updateProjectHandler updates a project.
+func updateProjectHandler(w http.ResponseWriter, r *http.Request) {
+	p, err := decodeProject(r)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if p.ID == "" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан идентификатор"})
+		return
+	}
+
+	err = store.UpdateProject(p)
+	if err != nil {
+		if err.Error() == "проект не найден" {
+			writeJson(w, http.StatusNotFound, map[string]string{"error": "Проект не найден"})
+		} else {
+			writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Ошибка обновления проекта: " + err.Error()})
+		}
+		return
+	}
+	writeJson(w, http.StatusOK, struct{}{})
+}
+
+// deleteProjectHandler deletes a project, deleting or reassigning its tasks.
+func deleteProjectHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	id := q.Get("id")
+	if id == "" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан идентификатор"})
+		return
+	}
+
+	cascade := false
+	switch q.Get("mode") {
+	case "", "reassign":
+	case "cascade":
+		cascade = true
+	default:
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "неверный параметр mode: допустимо cascade или reassign"})
+		return
+	}
+	to := q.Get("to")
+	if to != "" {
+		if cascade {
+			writeJson(w, http.StatusBadRequest, map[string]string{"error": "параметр to допустим только с mode=reassign"})
+			return
+		}
+		target, err := findProject(storeFor(r), to)
+		if err != nil || target.ID == id {
+			writeJson(w, http.StatusBadRequest, map[string]string{"error": "неверный параметр to: проект не найден"})
+			return
+		}
+		to = target.ID
+	}
+
+	err := storeFor(r).DeleteProject(id, cascade, to)
+	if err != nil {
+		if err.Error() == "проект не найден" {
+			writeJson(w, http.StatusNotFound, map[string]string{"error": "Проект не найден"})
+		} else {
+			writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Ошибка удаления проекта: " + err.Error()})
+		}
+		return
+	}
+	writeJson(w, http.StatusOK, struct{}{})
+}
+
+// taskMoveHandler moves a task to another project.
+//
+// Method: POST /api/task/move?id=<id>&project=<project id>
+// An empty project removes the task from its project.
+// The optional If-Match header (the ETag of GET /api/task) makes the move conditional:
+// if the task has changed since, the response is 412 with the current task as for PUT /api/task.
+// On success the ETag header carries the new version.
+func taskMoveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Метод не поддерживается"})
+		return
+	}
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан идентификатор"})
+		return
+	}
+	version, _, err := ifMatchVersion(r)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	tasks := storeFor(r)
+	task, err := tasks.Get(id)
+	if err != nil {
+		writeJson(w, http.StatusNotFound, map[string]string{"error": "Задача не найдена"})
+		return
+	}
+
+	project := r.URL.Query().Get("project")
+	if err := checkProject(tasks, project, task.ProjectID); err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	version, err = tasks.MoveTask(task.ID, project, version)
+	if err != nil {
+		switch err.Error() {
+		case "задача не найдена":
+			writeJson(w, http.StatusNotFound, map[string]string{"error": "Задача не найдена"})
+		case "версия задачи устарела":
+			writeVersionConflict(w, tasks, task.ID, true)
+		default:
+			writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Ошибка перемещения задачи: " + err.Error()})
+		}
+		return
+	}
+	w.Header().Set("ETag", taskETag(version))
+	writeJson(w, http.StatusOK, struct{}{})
+}
+
+// findProject returns a project by a numeric id.
+func findProject(projects db.ProjectStore, id string) (*db.Project, error) {
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return nil, errors.New("проект не найден")
+	}
+	return projects.GetProject(id)
+}
+
+// checkProject validates the project of a task: it must exist, and a task
+// can be put into an archived project only if it is already there (current).
+func checkProject(projects db.ProjectStore, projectID, current string) error {
+	if projectID == "" || projectID == current {
+		return nil
+	}
+	project, err := findProject(projects, projectID)
+	if err != nil {
+		return fmt.Errorf("проект %s не найден", projectID)
+	}
+	if project.Archived {
+		return fmt.Errorf("проект %q в архиве", project.Name)
+	}
+	return nil
+}
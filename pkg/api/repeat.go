@@ -0,0 +1,489 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RepeatUnit identifies the kind of a repeat rule (the first token of the rule string).
+type RepeatUnit string
+
+const (
+	// RepeatDaily repeats every N days: "d <N>".
+	RepeatDaily RepeatUnit = "d"
+	// RepeatBusiness repeats every N working days (weekends and holidays are skipped): "b <N>".
+	RepeatBusiness RepeatUnit = "b"
+	// RepeatWeekly repeats on the given weekdays every N weeks: "w <weekdays> [/N]".
+	RepeatWeekly RepeatUnit = "w"
+	// RepeatMonthly repeats on the given days of month or ordinal weekdays: "m <days> [months]".
+	RepeatMonthly RepeatUnit = "m"
+	// RepeatYearly repeats every year on the same date: "y".
+	RepeatYearly RepeatUnit = "y"
+	// RepeatRRule is an RFC 5545 recurrence rule: "FREQ=WEEKLY;BYDAY=MO,WE".
+	RepeatRRule RepeatUnit = "RRULE"
+)
+
+// maxRepeatDays is the upper bound for the "d <N>" interval.
+const maxRepeatDays = 400
+
+// maxRepeatWeeks is the upper bound for the "w <weekdays> /N" interval.
+const maxRepeatWeeks = 52
+
+// RepeatRule is the parsed form of a repeat rule string.
+//
+// Only the fields relevant to Unit are set:
+//   - d: Interval (1..400)
+//   - b: Interval in working days (1..400); Holidays is filled by NextDate from the holiday calendar
+//   - w: Weekdays (1..7, Monday is 1) and Interval in weeks (1..52, 1 by default)
+//   - m: MonthDays (1..31, -1 is the last day, -2 is the day before the last),
+//     MonthWeekdays (e.g. the second Tuesday) and optional Months (1..12, empty means every month)
+//   - y: no parameters
+//   - RRULE: RRule
+type RepeatRule struct {
+	Unit          RepeatUnit
+	Interval      int
+	Weekdays      []int
+	MonthDays     []int
+	MonthWeekdays []MonthWeekday
+	Months        []int
+	RRule         *RRule
+	Holidays      map[string]bool
+}
+
+// MonthWeekday is an ordinal weekday within a month, written as "<weekday>#<n>" in the "m" rule.
+//
+// Weekday is 1..7 (Monday is 1); N is 1..5 counting from the start of the month
+// or -1..-5 counting from its end (-1 is the last such weekday).
+type MonthWeekday struct {
+	Weekday int
+	N       int
+}
+
+func (mw MonthWeekday) String() string {
+	return fmt.Sprintf("%d#%d", mw.Weekday, mw.N)
+}
+
+// RepeatError describes a repeat rule syntax error.
+//
+// Pos is the 1-based position (in characters) of the offending token in the rule string.
+type RepeatError struct {
+	Pos int
+	Msg string
+}
+
+func (e *RepeatError) Error() string {
+	return fmt.Sprintf("позиция %d: %s", e.Pos, e.Msg)
+}
+
+// repeatToken is a whitespace-separated part of a rule string with its position.
+type repeatToken struct {
+	text string
+	pos  int
+}
+
+// tokenizeRepeat splits a rule string by whitespace keeping 1-based positions of tokens.
+func tokenizeRepeat(s string) []repeatToken {
+	var tokens []repeatToken
+	start := -1
+	pos := 0
+	for i, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+			if start >= 0 {
+				tokens = append(tokens, repeatToken{text: s[start:i], pos: pos})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+			pos = len([]rune(s[:i])) + 1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, repeatToken{text: s[start:], pos: pos})
+	}
+	return tokens
+}
+
+// parseRepeatList parses a comma-separated list of integers validated by check.
+//
+// check returns an empty string for a valid value or a description of the problem.
+func parseRepeatList(tok repeatToken, what string, check func(int) string) ([]int, error) {
+	var values []int
+	for _, item := range splitRepeatList(tok) {
+		num, err := parseRepeatInt(item, what, check)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, num)
+	}
+	return values, nil
+}
+
+// splitRepeatList splits a comma-separated token into items keeping their positions.
+func splitRepeatList(tok repeatToken) []repeatToken {
+	var items []repeatToken
+	pos := tok.pos
+	for _, item := range strings.Split(tok.text, ",") {
+		items = append(items, repeatToken{text: item, pos: pos})
+		pos += len([]rune(item)) + 1
+	}
+	return items
+}
+
+// parseRepeatInt parses a single list item validated by check (see parseRepeatList).
+func parseRepeatInt(item repeatToken, what string, check func(int) string) (int, error) {
+	if item.text == "" {
+		return 0, &RepeatError{Pos: item.pos, Msg: fmt.Sprintf("пустое значение в списке: %s", what)}
+	}
+	num, err := strconv.Atoi(item.text)
+	if err != nil {
+		return 0, &RepeatError{Pos: item.pos, Msg: fmt.Sprintf("%s должен быть числом, получено %q", what, item.text)}
+	}
+	if msg := check(num); msg != "" {
+		return 0, &RepeatError{Pos: item.pos, Msg: fmt.Sprintf("%s %s, получено %d", what, msg, num)}
+	}
+	return num, nil
+}
+
+// parseMonthDays parses the days list of the "m" rule: day numbers and
+// ordinal weekdays ("2#2" is the second Tuesday, "5#-1" is the last Friday).
+func parseMonthDays(tok repeatToken) ([]int, []MonthWeekday, error) {
+	var days []int
+	var weekdays []MonthWeekday
+	for _, item := range splitRepeatList(tok) {
+		weekdayStr, nStr, ordinal := strings.Cut(item.text, "#")
+		if !ordinal {
+			day, err := parseRepeatInt(item, "день месяца", func(n int) string {
+				if n < -2 || n == 0 || n > 31 {
+					return "должен быть от 1 до 31, -1 или -2"
+				}
+				return ""
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			days = append(days, day)
+			continue
+		}
+
+		weekday, err := parseRepeatInt(repeatToken{text: weekdayStr, pos: item.pos}, "день недели", func(n int) string {
+			if n < 1 || n > 7 {
+				return "должен быть от 1 до 7"
+			}
+			return ""
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		nPos := item.pos + len([]rune(weekdayStr)) + 1
+		n, err := parseRepeatInt(repeatToken{text: nStr, pos: nPos}, "номер дня недели в месяце", func(n int) string {
+			if n < -5 || n == 0 || n > 5 {
+				return "должен быть от 1 до 5 или от -5 до -1"
+			}
+			return ""
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		weekdays = append(weekdays, MonthWeekday{Weekday: weekday, N: n})
+	}
+	return days, weekdays, nil
+}
+
+// ParseRepeat parses a repeat rule string.
+//
+// Supported formats:
+//   - d <N>           every N days (1..400)
+//   - b <N>           every N working days (1..400), weekends and holidays are skipped
+//   - w <list> [/N]   weekly on weekdays (1..7), e.g. "w 1,3,5";
+//     with "/N" every N-th week counting from the week of the start date: "w 1,4 /2"
+//   - m <days> [mons] monthly on day numbers, e.g. "m 1,15" or "m -1" (last day)
+//     or ordinal weekdays "<weekday>#<n>", e.g. "m 2#2" (second Tuesday) or "m 5#-1" (last Friday);
+//     optional months list: "m 1,15 1,6,12", "m 1#1 1,4,7,10"
+//   - y               yearly
+//   - FREQ=...;...    RFC 5545 RRULE, optionally prefixed with "RRULE:" (see RRule)
+func ParseRepeat(repeat string) (RepeatRule, error) {
+	if isRRule(repeat) {
+		rrule, err := parseRRule(repeat)
+		if err != nil {
+			return RepeatRule{}, err
+		}
+		return RepeatRule{Unit: RepeatRRule, RRule: rrule}, nil
+	}
+
+	tokens := tokenizeRepeat(repeat)
+	if len(tokens) == 0 {
+		return RepeatRule{}, &RepeatError{Pos: 1, Msg: "правило повторения не должно быть пустым"}
+	}
+
+	unit := tokens[0]
+	rule := RepeatRule{Unit: RepeatUnit(unit.text)}
+
+	// argCount checks that the rule has between min and max parameters.
+	argCount := func(min, max int, what string) error {
+		args := len(tokens) - 1
+		if args < min {
+			return &RepeatError{Pos: len([]rune(repeat)) + 1, Msg: "отсутствует " + what}
+		}
+		if args > max {
+			return &RepeatError{Pos: tokens[max+1].pos, Msg: fmt.Sprintf("лишний параметр %q", tokens[max+1].text)}
+		}
+		return nil
+	}
+
+	switch rule.Unit {
+	case RepeatDaily, RepeatBusiness:
+		if err := argCount(1, 1, "число дней для правила "+unit.text); err != nil {
+			return RepeatRule{}, err
+		}
+		tok := tokens[1]
+		days, err := strconv.Atoi(tok.text)
+		if err != nil {
+			return RepeatRule{}, &RepeatError{Pos: tok.pos, Msg: fmt.Sprintf("число дней должно быть числом, получено %q", tok.text)}
+		}
+		if days < 1 || days > maxRepeatDays {
+			return RepeatRule{}, &RepeatError{Pos: tok.pos, Msg: fmt.Sprintf("число дней должно быть от 1 до %d, получено %d", maxRepeatDays, days)}
+		}
+		rule.Interval = days
+
+	case RepeatWeekly:
+		if err := argCount(1, 2, "список дней недели для правила w"); err != nil {
+			return RepeatRule{}, err
+		}
+		weekdays, err := parseRepeatList(tokens[1], "день недели", func(n int) string {
+			if n < 1 || n > 7 {
+				return "должен быть от 1 до 7"
+			}
+			return ""
+		})
+		if err != nil {
+			return RepeatRule{}, err
+		}
+		rule.Weekdays = weekdays
+
+		rule.Interval = 1
+		if len(tokens) == 3 {
+			tok := tokens[2]
+			if !strings.HasPrefix(tok.text, "/") {
+				return RepeatRule{}, &RepeatError{Pos: tok.pos, Msg: fmt.Sprintf("интервал недель должен иметь вид /N, получено %q", tok.text)}
+			}
+			weeks, err := strconv.Atoi(tok.text[1:])
+			if err != nil || weeks < 1 || weeks > maxRepeatWeeks {
+				return RepeatRule{}, &RepeatError{Pos: tok.pos + 1, Msg: fmt.Sprintf("интервал недель должен быть от 1 до %d, получено %q", maxRepeatWeeks, tok.text[1:])}
+			}
+			rule.Interval = weeks
+		}
+
+	case RepeatMonthly:
+		if err := argCount(1, 2, "список дней месяца для правила m"); err != nil {
+			return RepeatRule{}, err
+		}
+		days, weekdays, err := parseMonthDays(tokens[1])
+		if err != nil {
+			return RepeatRule{}, err
+		}
+		rule.MonthDays = days
+		rule.MonthWeekdays = weekdays
+
+		if len(tokens) == 3 {
+			months, err := parseRepeatList(tokens[2], "месяц", func(n int) string {
+				if n < 1 || n > 12 {
+					return "должен быть от 1 до 12"
+				}
+				return ""
+			})
+			if err != nil {
+				return RepeatRule{}, err
+			}
+			rule.Months = months
+		}
+
+		if !rule.monthlyPossible() {
+			return RepeatRule{}, &RepeatError{Pos: tokens[1].pos, Msg: "ни один из дней месяца не встречается в указанных месяцах"}
+		}
+
+	case RepeatYearly:
+		if err := argCount(0, 0, ""); err != nil {
+			return RepeatRule{}, err
+		}
+
+	default:
+		return RepeatRule{}, &RepeatError{Pos: unit.pos, Msg: fmt.Sprintf("неподдерживаемый формат правила повторения: %s", unit.text)}
+	}
+
+	return rule, nil
+}
+
+// String returns the canonical string form of the rule.
+// ParseRepeat(rule.String()) yields an equal rule.
+func (r RepeatRule) String() string {
+	switch r.Unit {
+	case RepeatDaily, RepeatBusiness:
+		return fmt.Sprintf("%s %d", r.Unit, r.Interval)
+	case RepeatWeekly:
+		s := "w " + joinInts(r.Weekdays)
+		if r.Interval > 1 {
+			s += fmt.Sprintf(" /%d", r.Interval)
+		}
+		return s
+	case RepeatMonthly:
+		items := make([]string, 0, len(r.MonthDays)+len(r.MonthWeekdays))
+		for _, d := range r.MonthDays {
+			items = append(items, strconv.Itoa(d))
+		}
+		for _, mw := range r.MonthWeekdays {
+			items = append(items, mw.String())
+		}
+		s := "m " + strings.Join(items, ",")
+		if len(r.Months) > 0 {
+			s += " " + joinInts(r.Months)
+		}
+		return s
+	case RepeatRRule:
+		return r.RRule.String()
+	default:
+		return string(r.Unit)
+	}
+}
+
+// Next returns the first occurrence of the rule strictly after the given date.
+//
+// For "d", "b", "y", "w .. /N" and RRULE the date is expected to be an occurrence itself
+// (or the start date): the rule steps forward from it by the interval.
+// The boolean result is false if no further occurrence exists.
+func (r RepeatRule) Next(after time.Time) (time.Time, bool) {
+	switch r.Unit {
+	case RepeatRRule:
+		return r.RRule.Next(after)
+
+	case RepeatDaily:
+		return after.AddDate(0, 0, r.Interval), true
+
+	case RepeatBusiness:
+		date := after
+		for left := r.Interval; left > 0; {
+			date = date.AddDate(0, 0, 1)
+			if date.Sub(after) > 10*366*24*time.Hour {
+				return time.Time{}, false
+			}
+			if isWorkday(date, r.Holidays) {
+				left--
+			}
+		}
+		return date, true
+
+	case RepeatYearly:
+		return after.AddDate(1, 0, 0), true
+
+	case RepeatWeekly:
+		var weekdays [8]bool
+		for _, w := range r.Weekdays {
+			weekdays[w] = true
+		}
+		// Look for the rest of the current week (Monday..Sunday) first,
+		// then continue from the Monday Interval weeks later.
+		date := after
+		for i := 0; i < 7; i++ {
+			date = date.AddDate(0, 0, 1)
+			if isoWeekday(date) == 1 && r.Interval > 1 {
+				date = date.AddDate(0, 0, 7*(r.Interval-1))
+			}
+			if weekdays[isoWeekday(date)] {
+				return date, true
+			}
+		}
+		return time.Time{}, false
+
+	case RepeatMonthly:
+		// A matching day is guaranteed within 28 years (the fifth weekday of February
+		// is the rarest case), the bound only protects against looping forever.
+		date := after
+		for i := 0; i < 366*29; i++ {
+			date = date.AddDate(0, 0, 1)
+			if r.matchesMonthly(date) {
+				return date, true
+			}
+		}
+		return time.Time{}, false
+	}
+
+	return time.Time{}, false
+}
+
+// matchesMonthly reports whether the date satisfies the "m" rule.
+func (r RepeatRule) matchesMonthly(date time.Time) bool {
+	if !r.monthSelected(int(date.Month())) {
+		return false
+	}
+	day := date.Day()
+	lastDay := daysInMonth(date.Year(), date.Month())
+	for _, d := range r.MonthDays {
+		if d == day || (d < 0 && lastDay+d+1 == day) {
+			return true
+		}
+	}
+	for _, mw := range r.MonthWeekdays {
+		if mw.Weekday != isoWeekday(date) {
+			continue
+		}
+		if (mw.N > 0 && (day-1)/7+1 == mw.N) || (mw.N < 0 && (lastDay-day)/7+1 == -mw.N) {
+			return true
+		}
+	}
+	return false
+}
+
+// monthSelected reports whether the month passes the optional month filter.
+func (r RepeatRule) monthSelected(month int) bool {
+	return len(r.Months) == 0 || containsInt(r.Months, month)
+}
+
+// monthlyPossible reports whether at least one of MonthDays exists in one of the selected months
+// (e.g. "m 30,31 2" can never match). Ordinal weekdays exist in every month sooner or later.
+func (r RepeatRule) monthlyPossible() bool {
+	return len(r.MonthWeekdays) > 0 || monthDaysPossible(r.MonthDays, r.Months)
+}
+
+// monthDaysPossible reports whether at least one of the days of month (negative ones count
+// from the end of the month) exists in one of the months; no months means every month.
+func monthDaysPossible(days, months []int) bool {
+	for m := 1; m <= 12; m++ {
+		if len(months) > 0 && !containsInt(months, m) {
+			continue
+		}
+		// 2024 is a leap year, so 29 February counts as possible.
+		lastDay := daysInMonth(2024, time.Month(m))
+		for _, d := range days {
+			if d <= lastDay && -d <= lastDay {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// joinInts formats a list of integers as a comma-separated string.
+func joinInts(values []int) string {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = strconv.Itoa(v)
+	}
+	return strings.Join(strs, ",")
+}
+
+// isoWeekday returns the weekday number with Monday = 1 ... Sunday = 7.
+func isoWeekday(date time.Time) int {
+	weekday := int(date.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	return weekday
+}
+
+// daysInMonth returns the number of days in the given month.
+func daysInMonth(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
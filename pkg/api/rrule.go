@@ -0,0 +1,486 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RRuleFreq is the FREQ part of an RFC 5545 recurrence rule.
+type RRuleFreq string
+
+const (
+	FreqDaily   RRuleFreq = "DAILY"
+	FreqWeekly  RRuleFreq = "WEEKLY"
+	FreqMonthly RRuleFreq = "MONTHLY"
+	FreqYearly  RRuleFreq = "YEARLY"
+)
+
+// maxRRulePeriods bounds the number of FREQ periods scanned when looking for the next occurrence.
+const maxRRulePeriods = 20000
+
+// maxRRuleYears bounds the dates scanned when looking for the next occurrence: rules that
+// never match although they pass parsing (e.g. BYMONTHDAY=1;BYDAY=2MO) stop there.
+// The period right after the date is always scanned, whatever INTERVAL is.
+const maxRRuleYears = 100
+
+// rruleWeekdays maps RFC 5545 weekday codes to time.Weekday.
+var rruleWeekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// RRuleDay is a BYDAY entry: a weekday with an optional ordinal,
+// e.g. "TU" (every Tuesday), "2TU" (second Tuesday) or "-1FR" (last Friday).
+type RRuleDay struct {
+	N       int
+	Weekday time.Weekday
+}
+
+func (d RRuleDay) String() string {
+	code := strings.ToUpper(d.Weekday.String()[:2])
+	if d.N == 0 {
+		return code
+	}
+	return strconv.Itoa(d.N) + code
+}
+
+// RRule is a parsed RFC 5545 recurrence rule, e.g.
+// "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10".
+//
+// Only date-level parts are supported: FREQ (DAILY, WEEKLY, MONTHLY, YEARLY),
+// INTERVAL, BYDAY, BYMONTHDAY, BYMONTH, BYSETPOS, WKST, COUNT and UNTIL.
+// COUNT includes the start date of the series (DTSTART), as in RFC 5545.
+//
+// An RRule caches the period it expanded last, so it must not be used by several
+// goroutines at once; ParseRepeat returns a new one every time.
+type RRule struct {
+	Freq       RRuleFreq
+	Interval   int
+	ByDay      []RRuleDay
+	ByMonthDay []int
+	ByMonth    []int
+	BySetPos   []int
+	WeekStart  time.Weekday
+	Count      int
+	Until      time.Time
+
+	expanded *rrulePeriod
+}
+
+// rrulePeriod is a FREQ period expanded for a reference date (see RRule.expand).
+type rrulePeriod struct {
+	start time.Time
+	ref   time.Time
+	dates []time.Time
+}
+
+// isRRule reports whether the repeat string looks like an RRULE rather than the d/w/m/y mini-language.
+func isRRule(repeat string) bool {
+	return strings.Contains(repeat, "=")
+}
+
+// parseRRule parses an RRULE string. An optional "RRULE:" prefix is accepted.
+//
+// Positions in returned RepeatError values are relative to the original string.
+func parseRRule(repeat string) (*RRule, error) {
+	body := strings.TrimSpace(repeat)
+	offset := len([]rune(repeat)) - len([]rune(strings.TrimLeft(repeat, " \t\r\n")))
+	if len(body) >= 6 && strings.EqualFold(body[:6], "RRULE:") {
+		body = body[6:]
+		offset += 6
+	}
+
+	rule := &RRule{Interval: 1, WeekStart: time.Monday}
+	seen := map[string]bool{}
+	pos := offset + 1
+
+	for _, part := range strings.Split(body, ";") {
+		partPos := pos
+		pos += len([]rune(part)) + 1
+
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || name == "" {
+			return nil, &RepeatError{Pos: partPos, Msg: fmt.Sprintf("ожидается ИМЯ=ЗНАЧЕНИЕ, получено %q", part)}
+		}
+		name = strings.ToUpper(strings.TrimSpace(name))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		valuePos := partPos + len([]rune(part)) - len([]rune(value))
+
+		if seen[name] {
+			return nil, &RepeatError{Pos: partPos, Msg: fmt.Sprintf("параметр %s указан повторно", name)}
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			switch RRuleFreq(value) {
+			case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+				rule.Freq = RRuleFreq(value)
+			default:
+				err = &RepeatError{Pos: valuePos, Msg: fmt.Sprintf("неподдерживаемое значение FREQ: %q", value)}
+			}
+		case "INTERVAL":
+			rule.Interval, err = parseRRuleInt(value, valuePos, name, 1, 1000)
+		case "COUNT":
+			rule.Count, err = parseRRuleInt(value, valuePos, name, 1, 100000)
+		case "UNTIL":
+			rule.Until, err = parseRRuleUntil(value, valuePos)
+		case "BYMONTH":
+			rule.ByMonth, err = parseRRuleInts(value, valuePos, name, 1, 12, false)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseRRuleInts(value, valuePos, name, -31, 31, true)
+		case "BYSETPOS":
+			rule.BySetPos, err = parseRRuleInts(value, valuePos, name, -366, 366, true)
+		case "BYDAY":
+			rule.ByDay, err = parseRRuleDays(value, valuePos)
+		case "WKST":
+			wd, ok := rruleWeekdays[value]
+			if !ok {
+				err = &RepeatError{Pos: valuePos, Msg: fmt.Sprintf("некорректный день недели WKST: %q", value)}
+			}
+			rule.WeekStart = wd
+		default:
+			err = &RepeatError{Pos: partPos, Msg: fmt.Sprintf("параметр %s не поддерживается", name)}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, &RepeatError{Pos: offset + 1, Msg: "отсутствует обязательный параметр FREQ"}
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, &RepeatError{Pos: offset + 1, Msg: "COUNT и UNTIL не могут использоваться вместе"}
+	}
+	if len(rule.BySetPos) > 0 && len(rule.ByDay) == 0 && len(rule.ByMonthDay) == 0 && len(rule.ByMonth) == 0 {
+		return nil, &RepeatError{Pos: offset + 1, Msg: "BYSETPOS используется только вместе с другими BY-параметрами"}
+	}
+	if rule.Freq == FreqWeekly && len(rule.ByMonthDay) > 0 {
+		return nil, &RepeatError{Pos: offset + 1, Msg: "BYMONTHDAY не используется с FREQ=WEEKLY"}
+	}
+	if len(rule.ByMonthDay) > 0 && !monthDaysPossible(rule.ByMonthDay, rule.ByMonth) {
+		return nil, &RepeatError{Pos: offset + 1, Msg: "ни один из дней BYMONTHDAY не встречается в месяцах BYMONTH"}
+	}
+	for _, d := range rule.ByDay {
+		if d.N == 0 {
+			continue
+		}
+		if rule.Freq != FreqMonthly && rule.Freq != FreqYearly {
+			return nil, &RepeatError{Pos: offset + 1, Msg: "порядковый номер в BYDAY допустим только для MONTHLY и YEARLY"}
+		}
+		if rule.Freq == FreqMonthly || len(rule.ByMonth) > 0 {
+			if d.N < -5 || d.N > 5 {
+				return nil, &RepeatError{Pos: offset + 1, Msg: fmt.Sprintf("порядковый номер дня недели в месяце должен быть от -5 до 5, получено %d", d.N)}
+			}
+		}
+	}
+
+	return rule, nil
+}
+
+// parseRRuleInt parses a single integer RRULE value within [min, max].
+func parseRRuleInt(value string, pos int, name string, min, max int) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, &RepeatError{Pos: pos, Msg: fmt.Sprintf("%s должен быть числом, получено %q", name, value)}
+	}
+	if n < min || n > max {
+		return 0, &RepeatError{Pos: pos, Msg: fmt.Sprintf("%s должен быть от %d до %d, получено %d", name, min, max, n)}
+	}
+	return n, nil
+}
+
+// parseRRuleInts parses a comma-separated list of integers within [min, max].
+// If nonZero is set, zero is rejected (used for values that may be negative).
+func parseRRuleInts(value string, pos int, name string, min, max int, nonZero bool) ([]int, error) {
+	var values []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := parseRRuleInt(item, pos, name, min, max)
+		if err != nil {
+			return nil, err
+		}
+		if nonZero && n == 0 {
+			return nil, &RepeatError{Pos: pos, Msg: fmt.Sprintf("%s не может быть равен 0", name)}
+		}
+		values = append(values, n)
+		pos += len([]rune(item)) + 1
+	}
+	return values, nil
+}
+
+// parseRRuleDays parses a BYDAY list, e.g. "MO,WE" or "2TU,-1FR".
+func parseRRuleDays(value string, pos int) ([]RRuleDay, error) {
+	var days []RRuleDay
+	for _, item := range strings.Split(value, ",") {
+		if len(item) < 2 {
+			return nil, &RepeatError{Pos: pos, Msg: fmt.Sprintf("некорректный день недели BYDAY: %q", item)}
+		}
+		code := item[len(item)-2:]
+		wd, ok := rruleWeekdays[code]
+		if !ok {
+			return nil, &RepeatError{Pos: pos, Msg: fmt.Sprintf("некорректный день недели BYDAY: %q", item)}
+		}
+		day := RRuleDay{Weekday: wd}
+		if ord := item[:len(item)-2]; ord != "" {
+			n, err := strconv.Atoi(ord)
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, &RepeatError{Pos: pos, Msg: fmt.Sprintf("некорректный порядковый номер в BYDAY: %q", item)}
+			}
+			day.N = n
+		}
+		days = append(days, day)
+		pos += len([]rune(item)) + 1
+	}
+	return days, nil
+}
+
+// parseRRuleUntil parses UNTIL as a date ("20240131") or a date-time ("20240131T235959Z").
+// Only the date part is used since tasks are scheduled by date.
+func parseRRuleUntil(value string, pos int) (time.Time, error) {
+	datePart := value
+	if i := strings.IndexByte(value, 'T'); i >= 0 {
+		datePart = value[:i]
+	}
+	until, err := time.Parse(DateFormat, datePart)
+	if err != nil {
+		return time.Time{}, &RepeatError{Pos: pos, Msg: fmt.Sprintf("некорректная дата UNTIL: %q", value)}
+	}
+	return until, nil
+}
+
+// String returns the canonical RRULE form (without the "RRULE:" prefix).
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = d.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.BySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(r.BySetPos))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+strings.ToUpper(r.WeekStart.String()[:2]))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format(DateFormat))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence strictly after the given date.
+//
+// The date is treated as an occurrence of the series: when BYDAY/BYMONTHDAY/BYMONTH
+// do not fix the day, its weekday, day of month and month are used (like DTSTART in RFC 5545),
+// and INTERVAL is counted from its period. COUNT is not applied here since it depends
+// on the start of the series; see NextDate.
+func (r *RRule) Next(after time.Time) (time.Time, bool) {
+	period := r.periodStart(after)
+	horizon := after.AddDate(maxRRuleYears, 0, 0)
+	for i := 0; i < maxRRulePeriods; i++ {
+		if !r.Until.IsZero() && period.After(r.Until) {
+			break
+		}
+		if i > 1 && period.After(horizon) {
+			break
+		}
+		for _, date := range r.expand(period, after) {
+			if !date.After(after) {
+				continue
+			}
+			if !r.Until.IsZero() && date.After(r.Until) {
+				return time.Time{}, false
+			}
+			return date, true
+		}
+		period = r.addPeriods(period, r.Interval)
+	}
+	return time.Time{}, false
+}
+
+// periodStart returns the first day of the FREQ period containing the date.
+func (r *RRule) periodStart(date time.Time) time.Time {
+	y, m, d := date.Date()
+	switch r.Freq {
+	case FreqWeekly:
+		shift := (int(date.Weekday()) - int(r.WeekStart) + 7) % 7
+		return time.Date(y, m, d-shift, 0, 0, 0, 0, time.UTC)
+	case FreqMonthly:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	case FreqYearly:
+		return time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// addPeriods moves a period start forward by n FREQ periods.
+func (r *RRule) addPeriods(period time.Time, n int) time.Time {
+	switch r.Freq {
+	case FreqWeekly:
+		return period.AddDate(0, 0, 7*n)
+	case FreqMonthly:
+		return period.AddDate(0, n, 0)
+	case FreqYearly:
+		return period.AddDate(n, 0, 0)
+	default:
+		return period.AddDate(0, 0, n)
+	}
+}
+
+// expand returns sorted occurrences within the period starting at the given date.
+// ref supplies the defaults that RFC 5545 takes from DTSTART. The result is cached:
+// stepping through a series looks into the same period again for every occurrence in it.
+func (r *RRule) expand(period, ref time.Time) []time.Time {
+	if c := r.expanded; c != nil && c.start.Equal(period) && r.sameRef(c.ref, ref) {
+		return c.dates
+	}
+	dates := r.expandPeriod(period, ref)
+	r.expanded = &rrulePeriod{start: period, ref: ref, dates: dates}
+	return dates
+}
+
+// sameRef reports whether two reference dates give the same occurrences (see matches):
+// only the parts of a date that BY* parts leave unfixed are taken from it.
+func (r *RRule) sameRef(a, b time.Time) bool {
+	switch {
+	case r.Freq == FreqWeekly && len(r.ByDay) == 0:
+		return a.Weekday() == b.Weekday()
+	case (r.Freq == FreqMonthly || r.Freq == FreqYearly) && len(r.ByDay) == 0 && len(r.ByMonthDay) == 0:
+		return a.Month() == b.Month() && a.Day() == b.Day()
+	}
+	return true
+}
+
+// expandPeriod computes the occurrences within a period (see expand).
+func (r *RRule) expandPeriod(period, ref time.Time) []time.Time {
+	end := r.addPeriods(period, 1)
+
+	var dates []time.Time
+	for date := period; date.Before(end); date = date.AddDate(0, 0, 1) {
+		if r.matches(date, ref) {
+			dates = append(dates, date)
+		}
+	}
+
+	if len(r.BySetPos) == 0 {
+		return dates
+	}
+	var selected []time.Time
+	for _, pos := range r.BySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(dates) + pos
+		}
+		if i >= 0 && i < len(dates) {
+			selected = append(selected, dates[i])
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Before(selected[j]) })
+	return selected
+}
+
+// matches reports whether the date satisfies the BY* parts of the rule.
+func (r *RRule) matches(date, ref time.Time) bool {
+	if len(r.ByMonth) > 0 && !containsInt(r.ByMonth, int(date.Month())) {
+		return false
+	}
+
+	if len(r.ByMonthDay) > 0 {
+		lastDay := daysInMonth(date.Year(), date.Month())
+		ok := false
+		for _, d := range r.ByMonthDay {
+			if d == date.Day() || (d < 0 && lastDay+d+1 == date.Day()) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	if len(r.ByDay) > 0 {
+		ok := false
+		for _, d := range r.ByDay {
+			if d.Weekday == date.Weekday() && (d.N == 0 || r.ordinalMatches(date, d.N)) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	// Without BY* parts fixing the day, take it from the reference occurrence.
+	switch r.Freq {
+	case FreqWeekly:
+		if len(r.ByDay) == 0 {
+			return date.Weekday() == ref.Weekday()
+		}
+	case FreqMonthly:
+		if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+			return date.Day() == ref.Day()
+		}
+	case FreqYearly:
+		if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+			if len(r.ByMonth) == 0 && date.Month() != ref.Month() {
+				return false
+			}
+			return date.Day() == ref.Day()
+		}
+	}
+	return true
+}
+
+// ordinalMatches reports whether the date is the n-th (or n-th from the end when negative)
+// weekday of its month, or of its year for YEARLY rules without BYMONTH.
+func (r *RRule) ordinalMatches(date time.Time, n int) bool {
+	y, m, _ := date.Date()
+	first := time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC)
+	if r.Freq == FreqYearly && len(r.ByMonth) == 0 {
+		first = time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC)
+		last = time.Date(y, time.December, 31, 0, 0, 0, 0, time.UTC)
+	}
+
+	if n > 0 {
+		return int(date.Sub(first).Hours()/24)/7+1 == n
+	}
+	return int(last.Sub(date).Hours()/24)/7+1 == -n
+}
+
+// containsInt reports whether values contains v.
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
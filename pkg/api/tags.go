@@ -0,0 +1,169 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+)
+
+// maxTagLength limits the length of a tag name in characters.
+const maxTagLength = 64
+
+// TagsResp is a response wrapper for GET /api/tags.
+type TagsResp struct {
+	Tags []*db.Tag `json:"tags"`
+}
+
+// tagsHandler is a multiplexer for the tag dictionary.
+//
+// Methods:
+//   - GET    /api/tags          list with the number of tasks per tag
+//   - POST   /api/tags          create, body: {"name": "..."}
+//   - PUT    /api/tags          rename, body: {"id": "...", "name": "..."}
+//   - DELETE /api/tags?id=<id>  delete (the tag is removed from all tasks)
+func tagsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		listTagsHandler(w, r)
+	case http.MethodPost:
+		addTagHandler(w, r)
+	case http.MethodPut:
+		renameTagHandler(w, r)
+	case http.MethodDelete:
+		deleteTagHandler(w, r)
+	default:
+		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Метод не поддерживается"})
+	}
+}
+
+// listTagsHandler returns all tags ordered by name.
+func listTagsHandler(w http.ResponseWriter, r *http.Request) {
+	tags, err := store.Tags()
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, TagsResp{Tags: tags})
+}
+
+// decodeTag reads a tag from the request body and normalizes its name.
+func decodeTag(r *http.Request) (*db.Tag, error) {
+	var tag db.Tag
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		return nil, fmt.Errorf("Ошибка десериализации JSON: %v", err)
+	}
+	name, err := normalizeTag(tag.Name)
+	if err != nil {
+		return nil, err
+	}
+	tag.Name = name
+	return &tag, nil
+}
+
+// addTagHandler creates a tag.
+func addTagHandler(w http.ResponseWriter, r *http.Request) {
+	tag, err := decodeTag(r)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	id, err := store.AddTag(tag.Name)
+	if err != nil {
+		if err.Error() == "тег уже существует" {
+			writeJson(w, http.StatusConflict, map[string]string{"error": "Тег уже существует"})
+		} else {
+			writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Ошибка сохранения тега: " + err.Error()})
+		}
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]string{"id": strconv.FormatInt(id, 10)})
+}
+
+// renameTagHandler renames a tag.
+func renameTagHandler(w http.ResponseWriter, r *http.Request) {
+	tag, err := decodeTag(r)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if tag.ID == "" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан идентификатор"})
+		return
+	}
+
+	err = storeFor(r).RenameTag(tag)
+	if err != nil {
+		switch err.Error() {
+		case "тег не найден":
+			writeJson(w, http.StatusNotFound, map[string]string{"error": "Тег не найден"})
+		case "тег уже существует":
+			writeJson(w, http.StatusConflict, map[string]string{"error": "Тег уже существует"})
+		default:
+			writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Ошибка обновления тега: " + err.Error()})
+		}
+		return
+	}
+	writeJson(w, http.StatusOK, struct{}{})
+}
+
+// deleteTagHandler deletes a tag by ID.
+func deleteTagHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан идентификатор"})
+		return
+	}
+
+	err := storeFor(r).DeleteTag(id)
+	if err != nil {
+		if err.Error() == "тег не найден" {
+			writeJson(w, http.StatusNotFound, map[string]string{"error": "Тег не найден"})
+		} else {
+			writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Ошибка удаления тега: " + err.Error()})
+		}
+		return
+	}
+	writeJson(w, http.StatusOK, struct{}{})
+}
+
+// normalizeTag trims and lowercases a tag name and validates it.
+// A leading "#" is dropped, so "#Работа" and "работа" are the same tag.
+func normalizeTag(name string) (string, error) {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	switch {
+	case name == "":
+		return "", errors.New("пустое имя тега")
+	case utf8.RuneCountInString(name) > maxTagLength:
+		return "", fmt.Errorf("имя тега длиннее %d символов", maxTagLength)
+	case strings.ContainsAny(name, ", \t\n#"):
+		return "", fmt.Errorf("недопустимое имя тега %q: пробелы, запятые и # не допускаются", name)
+	}
+	return name, nil
+}
+
+// checkTags normalizes task tags: names are validated, sorted and deduplicated.
+// Nil tags stay nil, so an update without "tags" keeps the stored ones.
+func checkTags(task *db.Task) error {
+	if task.Tags == nil {
+		return nil
+	}
+	tags := make([]string, 0, len(task.Tags))
+	for _, name := range task.Tags {
+		name, err := normalizeTag(name)
+		if err != nil {
+			return err
+		}
+		tags = append(tags, name)
+	}
+	slices.Sort(tags)
+	task.Tags = slices.Compact(tags)
+	return nil
+}
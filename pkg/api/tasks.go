@@ -1,44 +1,235 @@
 package api
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
 )
 
+// Limits of the task list page size.
+const (
+	defaultTasksLimit = 50
+	maxTasksLimit     = 500
+)
+
 // TasksResp is a response wrapper for GET /api/tasks.
+// NextCursor is set when there may be more tasks after this page.
 type TasksResp struct {
-	Tasks []*db.Task `json:"tasks"`
+	Tasks      []*db.Task `json:"tasks"`
+	NextCursor string     `json:"next_cursor,omitempty"`
 }
 
 // tasksHandler returns a list of tasks.
 //
 // Method: GET /api/tasks
 // Query:
-//   - search (optional): if set, tasks are filtered by substring or by date.
+//   - search (optional): a query in the search language (see db.ParseSearch), e.g.
+//     `title:отчёт repeat:w before:20261201 -comment:draft`; text terms are matched
+//     with full-text search, results carry a highlighted snippet and are ranked by relevance.
+//   - from, to (optional): date range YYYYMMDD, inclusive.
+//   - repeating (optional): true for repeating tasks only, false for one-off tasks only.
+//   - overdue (optional): true for tasks dated before today.
+//   - due_within (optional): N, tasks dated from today to today + N days.
+//   - tag (optional, repeatable): tasks having every given tag.
+//   - project (optional): tasks of the project with this id, or "none" for tasks without a project.
+//   - archived (optional): true to include tasks of archived projects (always included with project).
+//   - limit (optional): page size, 1..500 (default 50).
+//   - sort (optional): date (default), title, id, rank (default for a text search) or smart:
+//     overdue tasks first (by priority, then date), then upcoming tasks by date, priority and time.
+//   - order (optional): asc (default) or desc.
+//   - cursor (optional): next_cursor of the previous page; sort and order must stay the same.
 func tasksHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Метод не поддерживается"})
 		return
 	}
 
-	limit := 50
-	search := r.URL.Query().Get("search")
-	var tasks []*db.Task
-	var err error
+	page, err := parsePage(r)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
 
-	if search == "" {
-		tasks, err = db.Tasks(limit)
-	} else {
-		tasks, err = db.SearchTasks(search, limit)
+	filter, err := parseFilter(r)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
 	}
 
+	tasks, err := store.List(filter, page)
+	var queryErr *db.QueryError
+	if errors.As(err, &queryErr) {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		writeJson(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
 
-	writeJson(w, http.StatusOK, TasksResp{
+	resp := TasksResp{
 		Tasks: tasks,
-	})
+	}
+	if len(tasks) == page.Limit {
+		next, err := page.Next(tasks[len(tasks)-1])
+		if err != nil {
+			writeJson(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		resp.NextCursor = encodeCursor(page, next)
+	}
+	writeJson(w, http.StatusOK, resp)
+}
+
+// parseFilter reads search and filter query parameters.
+// "Today" for overdue and due_within is the current date in the default time zone (TODO_TZ).
+func parseFilter(r *http.Request) (db.Filter, error) {
+	q := r.URL.Query()
+	filter := db.Filter{
+		Search: q.Get("search"),
+		From:   q.Get("from"),
+		To:     q.Get("to"),
+		Today:  time.Now().In(GetConfig().Location()).Format(DateFormat),
+	}
+
+	for name, value := range map[string]string{"from": filter.From, "to": filter.To} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(DateFormat, value); err != nil {
+			return filter, fmt.Errorf("неверный параметр %s: %v", name, err)
+		}
+	}
+	if v := q.Get("repeating"); v != "" {
+		repeating, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, errors.New("неверный параметр repeating: допустимо true или false")
+		}
+		filter.Repeating = &repeating
+	}
+	if v := q.Get("overdue"); v != "" {
+		overdue, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, errors.New("неверный параметр overdue: допустимо true или false")
+		}
+		filter.Overdue = overdue
+	}
+	if v := q.Get("due_within"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 0 || days > maxRepeatDays {
+			return filter, fmt.Errorf("неверный параметр due_within: ожидается число дней от 0 до %d", maxRepeatDays)
+		}
+		filter.DueWithin = &days
+	}
+	for _, v := range q["tag"] {
+		tag, err := normalizeTag(v)
+		if err != nil {
+			return filter, fmt.Errorf("неверный параметр tag: %v", err)
+		}
+		filter.Tags = append(filter.Tags, tag)
+	}
+	if v := q.Get("project"); v != "" {
+		if _, err := strconv.ParseInt(v, 10, 64); err != nil && v != db.NoProject {
+			return filter, errors.New("неверный параметр project: ожидается id проекта или none")
+		}
+		filter.Project = v
+	}
+	if v := q.Get("archived"); v != "" {
+		archived, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, errors.New("неверный параметр archived: допустимо true или false")
+		}
+		filter.Archived = archived
+	}
+	return filter, nil
+}
+
+// parsePage reads limit, sort, order and cursor query parameters.
+func parsePage(r *http.Request) (db.Page, error) {
+	q := r.URL.Query()
+	page := db.Page{
+		Limit: defaultTasksLimit,
+		Sort:  q.Get("sort"),
+		Today: time.Now().In(GetConfig().Location()).Format(DateFormat),
+	}
+	if page.Sort == "" {
+		page.Sort = db.SortDate
+		// Text search results are ranked by relevance unless another order is asked for.
+		if search := q.Get("search"); strings.TrimSpace(search) != "" {
+			query, err := db.ParseSearch(search)
+			if err != nil {
+				return page, err
+			}
+			if query.FullText() {
+				page.Sort = db.SortRank
+			}
+		}
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxTasksLimit {
+			return page, fmt.Errorf("неверный параметр limit: ожидается число от 1 до %d", maxTasksLimit)
+		}
+		page.Limit = limit
+	}
+	if err := db.CheckSort(page.Sort); err != nil {
+		return page, err
+	}
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		page.Desc = true
+	default:
+		return page, errors.New("неверный параметр order: допустимо asc или desc")
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		cursor, err := decodeCursor(page, v)
+		if err != nil {
+			return page, err
+		}
+		page.After = cursor
+	}
+	return page, nil
+}
+
+// pageCursor is the JSON payload of an opaque cursor.
+// Sort and order are kept to reject a cursor used with a different ordering.
+type pageCursor struct {
+	Sort string `json:"s"`
+	Desc bool   `json:"d,omitempty"`
+	Key  string `json:"k"`
+	ID   int64  `json:"i"`
+}
+
+// encodeCursor packs a keyset position into an opaque URL-safe string.
+func encodeCursor(page db.Page, c *db.Cursor) string {
+	data, _ := json.Marshal(pageCursor{Sort: page.Sort, Desc: page.Desc, Key: c.Key, ID: c.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor unpacks a cursor and checks that it belongs to the same ordering.
+func decodeCursor(page db.Page, value string) (*db.Cursor, error) {
+	invalid := errors.New("неверный параметр cursor")
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, invalid
+	}
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, invalid
+	}
+	if c.Sort != page.Sort || c.Desc != page.Desc {
+		return nil, errors.New("параметр cursor получен для другой сортировки")
+	}
+	return &db.Cursor{Key: c.Key, ID: c.ID}, nil
 }
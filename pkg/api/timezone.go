@@ -0,0 +1,65 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+
+	// Embed the IANA time zone database so TODO_TZ and per-task zones
+	// work in minimal containers without system tzdata.
+	_ "time/tzdata"
+)
+
+// TimeFormat is the format of the optional task time-of-day (HH:MM).
+const TimeFormat = "15:04"
+
+// checkTime validates task.Time and task.TZ.
+func checkTime(task *db.Task) error {
+	if task.Time != "" {
+		if _, err := time.Parse(TimeFormat, task.Time); err != nil {
+			return fmt.Errorf("некорректное время %q, ожидается ЧЧ:ММ", task.Time)
+		}
+	}
+	if task.TZ != "" {
+		if _, err := time.LoadLocation(task.TZ); err != nil {
+			return fmt.Errorf("некорректный часовой пояс %q: %v", task.TZ, err)
+		}
+	}
+	return nil
+}
+
+// taskLocation returns the time zone of the task: its own tz or the configured default.
+func taskLocation(task *db.Task) *time.Location {
+	if task.TZ != "" {
+		if loc, err := time.LoadLocation(task.TZ); err == nil {
+			return loc
+		}
+	}
+	return GetConfig().Location()
+}
+
+// taskNow returns the reference point for date comparisons of the task.
+//
+// It is the current time in the task's zone. If the task has a time of day
+// that has not come yet today, the reference point is moved to the previous day,
+// so that today's occurrence still counts as upcoming for NextDate.
+// The due moment is built with time.Date, so DST transitions are handled by the zone rules.
+func taskNow(now time.Time, task *db.Task) time.Time {
+	loc := taskLocation(task)
+	now = now.In(loc)
+	if task.Time == "" {
+		return now
+	}
+
+	clock, err := time.Parse(TimeFormat, task.Time)
+	if err != nil {
+		return now
+	}
+	y, m, d := now.Date()
+	due := time.Date(y, m, d, clock.Hour(), clock.Minute(), 0, 0, loc)
+	if now.Before(due) {
+		return now.AddDate(0, 0, -1)
+	}
+	return now
+}
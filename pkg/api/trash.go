@@ -0,0 +1,101 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// trashPurgeInterval is how often expired tasks are purged from the trash.
+const trashPurgeInterval = time.Hour
+
+// trashHandler is a multiplexer for the trash: deleted tasks and non-repeating tasks marked as done.
+//
+// Methods:
+//   - GET    /api/trash          tasks in the trash, the most recently deleted first
+//   - DELETE /api/trash?id=<id>  delete a task permanently; without id the whole trash is emptied
+func trashHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		listTrashHandler(w, r)
+	case http.MethodDelete:
+		purgeHandler(w, r)
+	default:
+		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Метод не поддерживается"})
+	}
+}
+
+// listTrashHandler returns the tasks in the trash.
+func listTrashHandler(w http.ResponseWriter, r *http.Request) {
+	tasks, err := store.Trash()
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, TasksResp{Tasks: tasks})
+}
+
+// purgeHandler deletes one task or all tasks in the trash permanently.
+func purgeHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		n, err := storeFor(r).PurgeTrash(time.Time{})
+		if err != nil {
+			writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Ошибка очистки корзины: " + err.Error()})
+			return
+		}
+		writeJson(w, http.StatusOK, map[string]int64{"purged": n})
+		return
+	}
+	writeTrashResult(w, storeFor(r).Purge(id), "Ошибка удаления задачи: ")
+}
+
+// trashRestoreHandler moves a task from the trash back to the task list.
+//
+// Method: POST /api/trash/restore?id=<id>
+func trashRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Метод не поддерживается"})
+		return
+	}
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Не указан идентификатор"})
+		return
+	}
+	writeTrashResult(w, storeFor(r).Restore(id), "Ошибка восстановления задачи: ")
+}
+
+// writeTrashResult writes the result of restoring or purging a task.
+func writeTrashResult(w http.ResponseWriter, err error, prefix string) {
+	switch {
+	case err == nil:
+		writeJson(w, http.StatusOK, struct{}{})
+	case err.Error() == "задача не найдена в корзине":
+		writeJson(w, http.StatusNotFound, map[string]string{"error": "Задача не найдена в корзине"})
+	default:
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": prefix + err.Error()})
+	}
+}
+
+// PurgeExpiredTrash permanently deletes tasks that have been in the trash longer than retention.
+// It runs once right away and then every trashPurgeInterval until stop is closed.
+func PurgeExpiredTrash(retention time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := store.PurgeTrash(time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("Ошибка очистки корзины: %v", err)
+		} else if n > 0 {
+			log.Printf("Из корзины удалено задач: %d", n)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
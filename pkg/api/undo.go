@@ -0,0 +1,163 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+)
+
+// undoWindow is how long after an action it can still be undone.
+const undoWindow = 10 * time.Minute
+
+// maxUndoDepth limits the number of actions remembered per session.
+const maxUndoDepth = 50
+
+// undoAction is a recorded action of a session with the operation reverting it.
+//
+// version is the version the task got from the action: once the task has changed
+// since, the action can no longer be undone. revert makes its writes through the given
+// store as a single store operation conditional on that version (see db.UndoStore).
+type undoAction struct {
+	action  string
+	taskID  string
+	version int64
+	at      time.Time
+	revert  func(tasks db.TaskStore, version int64) error
+}
+
+// undoHistory keeps a stack of recent actions for every session.
+// A session is identified by its JWT token (all requests share one session
+// when authentication is disabled).
+type undoHistory struct {
+	mu     sync.Mutex
+	stacks map[string][]undoAction
+}
+
+// undo is the undo history of the served store; it is reset by NewMux.
+var undo = newUndoHistory()
+
+// newUndoHistory returns an empty undo history.
+func newUndoHistory() *undoHistory {
+	return &undoHistory{stacks: map[string][]undoAction{}}
+}
+
+// push records an action of a session and forgets the actions that can no longer be undone.
+func (h *undoHistory) push(session string, a undoAction) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s, stack := range h.stacks {
+		stack = expire(stack, a.at)
+		if len(stack) == 0 {
+			delete(h.stacks, s)
+		} else {
+			h.stacks[s] = stack
+		}
+	}
+	stack := append(h.stacks[session], a)
+	if len(stack) > maxUndoDepth {
+		stack = stack[len(stack)-maxUndoDepth:]
+	}
+	h.stacks[session] = stack
+}
+
+// pop removes and returns the most recent action of a session that can still be undone.
+func (h *undoHistory) pop(session string, now time.Time) (undoAction, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stack := expire(h.stacks[session], now)
+	if len(stack) == 0 {
+		delete(h.stacks, session)
+		return undoAction{}, false
+	}
+	a := stack[len(stack)-1]
+	h.stacks[session] = stack[:len(stack)-1]
+	return a, true
+}
+
+// expire drops the actions done more than undoWindow before now; stack is ordered by time.
+func expire(stack []undoAction, now time.Time) []undoAction {
+	for i, a := range stack {
+		if now.Sub(a.at) <= undoWindow {
+			return stack[i:]
+		}
+	}
+	return nil
+}
+
+// recordUndo remembers how to revert an action of the request session
+// that left the task at the given version.
+func recordUndo(r *http.Request, action, taskID string, version int64, revert func(db.TaskStore, int64) error) {
+	undo.push(requestToken(r), undoAction{action: action, taskID: taskID, version: version, at: time.Now(), revert: revert})
+}
+
+// undoHandler reverts the most recent action of the session done within undoWindow:
+// adding, updating, deleting or completing a task.
+//
+// Method: POST /api/undo
+// Result: {"action": "add|update|delete|done", "id": "<task id>"}
+//
+// An action is not undone (409) if the task has changed since: the undo would
+// overwrite a later change. The revert runs in a single transaction conditional on
+// the task version, so it either applies as a whole or changes nothing.
+func undoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "Метод не поддерживается"})
+		return
+	}
+	a, ok := undo.pop(requestToken(r), time.Now())
+	if !ok {
+		writeJson(w, http.StatusConflict, map[string]string{"error": "Нет действий для отмены"})
+		return
+	}
+	if err := a.revert(storeFor(r), a.version); err != nil {
+		switch err.Error() {
+		case "версия задачи устарела", "задача не найдена":
+			writeJson(w, http.StatusConflict, map[string]string{"error": "Задача изменена после этого действия: отменить его нельзя"})
+		default:
+			writeJson(w, http.StatusInternalServerError, map[string]string{"error": "Не удалось отменить действие: " + err.Error()})
+		}
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]string{"action": a.action, "id": a.taskID})
+}
+
+// undoAdd returns the operation reverting adding a task: it is deleted permanently.
+func undoAdd(id string) func(db.TaskStore, int64) error {
+	return func(tasks db.TaskStore, version int64) error {
+		return tasks.RevertAdd(id, version)
+	}
+}
+
+// undoUpdate returns the operation putting back a task as it was before an update,
+// including its tags.
+func undoUpdate(old *db.Task) func(db.TaskStore, int64) error {
+	return func(tasks db.TaskStore, version int64) error {
+		t := *old
+		t.Version = version
+		if t.Tags == nil {
+			t.Tags = []string{}
+		}
+		return tasks.Update(&t)
+	}
+}
+
+// undoDelete returns the operation bringing a deleted task back from the trash
+// together with the dependencies it had (dependents lists the tasks it blocked).
+func undoDelete(old *db.Task, dependents []string) func(db.TaskStore, int64) error {
+	return func(tasks db.TaskStore, version int64) error {
+		return tasks.RevertDelete(old, dependents, version)
+	}
+}
+
+// undoDone returns the operation reverting marking a task as done: the task is brought
+// back from the trash (if the done ended its series) or to its previous date and repeat count,
+// with its checklist and dependencies, and the completion is removed from the history.
+func undoDone(old *db.Task, dependents []string, completionID string) func(db.TaskStore, int64) error {
+	return func(tasks db.TaskStore, version int64) error {
+		return tasks.RevertDone(old, dependents, completionID, version)
+	}
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+)
+
+// Roll policies for task dates falling on a weekend or a holiday.
+const (
+	// RollNone keeps the date as is.
+	RollNone = ""
+	// RollForward moves the date to the next working day.
+	RollForward = "forward"
+	// RollBackward moves the date to the previous working day
+	// (or forward if that day is already in the past).
+	RollBackward = "backward"
+)
+
+// isWorkday reports whether the date is neither a weekend nor a holiday.
+func isWorkday(date time.Time, holidays map[string]bool) bool {
+	if isoWeekday(date) > 5 {
+		return false
+	}
+	return !holidays[date.Format(DateFormat)]
+}
+
+// holidayDates loads the set of holiday dates; a nil calendar has none.
+func holidayDates(calendar db.HolidayStore) (map[string]bool, error) {
+	if calendar == nil {
+		return map[string]bool{}, nil
+	}
+	holidays, err := calendar.HolidayDates()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось загрузить календарь праздников: %v", err)
+	}
+	return holidays, nil
+}
+
+// checkRoll validates a roll policy.
+func checkRoll(policy string) error {
+	switch policy {
+	case RollNone, RollForward, RollBackward:
+		return nil
+	}
+	return fmt.Errorf("некорректная политика переноса roll: %q (допустимо forward или backward)", policy)
+}
+
+// RollDate moves the date (YYYYMMDD) off weekends and holidays according to the policy.
+//
+// Backward rolling never produces a date before now: in that case the date is rolled forward instead.
+// A nil holidays calendar means only weekends are skipped.
+func RollDate(now time.Time, date string, policy string, calendar db.HolidayStore) (string, error) {
+	if err := checkRoll(policy); err != nil {
+		return "", err
+	}
+	if policy == RollNone {
+		return date, nil
+	}
+
+	t, err := time.Parse(DateFormat, date)
+	if err != nil {
+		return "", fmt.Errorf("некорректная дата: %v", err)
+	}
+	holidays, err := holidayDates(calendar)
+	if err != nil {
+		return "", err
+	}
+
+	// roll steps one day at a time until a working day; the bound protects
+	// against a calendar where every day is a holiday.
+	roll := func(t time.Time, step int) (time.Time, error) {
+		for i := 0; !isWorkday(t, holidays); i++ {
+			if i > 366 {
+				return time.Time{}, fmt.Errorf("не удалось найти рабочий день рядом с %s", date)
+			}
+			t = t.AddDate(0, 0, step)
+		}
+		return t, nil
+	}
+
+	if policy == RollBackward {
+		rolled, err := roll(t, -1)
+		if err != nil {
+			return "", err
+		}
+		if rolled.Format(DateFormat) >= now.Format(DateFormat) {
+			return rolled.Format(DateFormat), nil
+		}
+	}
+
+	rolled, err := roll(t, 1)
+	if err != nil {
+		return "", err
+	}
+	return rolled.Format(DateFormat), nil
+}
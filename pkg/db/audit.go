@@ -0,0 +1,246 @@
+package db
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SystemActor is recorded in the audit log for writes not made on behalf of a user,
+// such as the scheduled trash cleanup.
+const SystemActor = "system"
+
+// AuditEntry is a change of a task recorded in the audit log.
+//
+// Action is one of "add", "update", "delete", "done", "reschedule", "move", "tag",
+// "checklist", "link", "unlink", "restore", "purge" and "undo" (see UndoStore).
+// At uses TimestampFormat. Before and After are JSON snapshots of the task (see Task)
+// around the change; Before is null for "add" and After is null for "purge"
+// and for an undone add.
+type AuditEntry struct {
+	ID     string          `json:"id"`
+	TaskID string          `json:"task_id"`
+	Action string          `json:"action"`
+	Actor  string          `json:"actor"`
+	At     string          `json:"at"`
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// AuditFilter selects audit log entries. Empty fields do not restrict the result;
+// From and To limit At to [From, To) and Limit (if positive) the number of entries.
+type AuditFilter struct {
+	TaskID string
+	Actor  string
+	Action string
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
+
+// match reports whether an entry passes the filter.
+func (f AuditFilter) match(e *AuditEntry) bool {
+	return (f.TaskID == "" || e.TaskID == f.TaskID) &&
+		(f.Actor == "" || e.Actor == f.Actor) &&
+		(f.Action == "" || e.Action == f.Action) &&
+		(f.From.IsZero() || e.At >= f.From.UTC().Format(TimestampFormat)) &&
+		(f.To.IsZero() || e.At < f.To.UTC().Format(TimestampFormat))
+}
+
+// auditActor returns the actor recorded for writes of a store view.
+func auditActor(actor string) string {
+	if actor == "" {
+		return SystemActor
+	}
+	return actor
+}
+
+// auditState encodes a task snapshot for the audit log; a missing task is null.
+func auditState(t *Task) (json.RawMessage, error) {
+	if t == nil {
+		return nil, nil
+	}
+	return json.Marshal(t)
+}
+
+// As returns a view of the store sharing its connection and recording writes under actor.
+func (s *SQLStore) As(actor string) TaskStore {
+	return &SQLStore{db: s.db, dialect: s.dialect, actor: actor}
+}
+
+// AuditLog returns the audit log entries matching the filter, the latest first.
+func (s *SQLStore) AuditLog(filter AuditFilter) ([]*AuditEntry, error) {
+	var where []string
+	var args []any
+	for _, f := range []struct{ column, value string }{
+		{"task_id = ?", filter.TaskID},
+		{"actor = ?", filter.Actor},
+		{"action = ?", filter.Action},
+	} {
+		if f.value != "" {
+			where = append(where, f.column)
+			args = append(args, f.value)
+		}
+	}
+	if !filter.From.IsZero() {
+		where = append(where, "at >= ?")
+		args = append(args, filter.From.UTC().Format(TimestampFormat))
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "at < ?")
+		args = append(args, filter.To.UTC().Format(TimestampFormat))
+	}
+
+	query := "SELECT id, task_id, action, actor, at, before_state, after_state FROM audit_log"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.Query(s.dialect.rebind(query), args...)
+	if err != nil {
+		return []*AuditEntry{}, err
+	}
+	defer rows.Close()
+
+	entries := []*AuditEntry{}
+	for rows.Next() {
+		e := &AuditEntry{}
+		var before, after sql.NullString
+		if err := rows.Scan(&e.ID, &e.TaskID, &e.Action, &e.Actor, &e.At, &before, &after); err != nil {
+			return []*AuditEntry{}, err
+		}
+		if before.Valid {
+			e.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			e.After = json.RawMessage(after.String)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return []*AuditEntry{}, err
+	}
+	return entries, nil
+}
+
+// track runs change within q and records an audit entry for each of the tasks
+// with the task state before and after the change.
+func (s *SQLStore) track(q execer, action string, ids []string, change func() error) error {
+	before := make([]*Task, len(ids))
+	for i, id := range ids {
+		t, err := s.snapshot(q, id)
+		if err != nil {
+			return err
+		}
+		before[i] = t
+	}
+
+	if err := change(); err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+		after, err := s.snapshot(q, id)
+		if err != nil {
+			return err
+		}
+		if err := s.audit(q, id, action, before[i], after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trackDependents runs change within q and records an "unlink" audit entry for each task
+// blocked by any of the tasks ids (and not among them) whose dependencies the change drops.
+func (s *SQLStore) trackDependents(q execer, ids []string, change func() error) error {
+	var dependents []string
+	for _, id := range ids {
+		found, err := s.taskIDs(q, "SELECT task_id FROM task_dependencies WHERE blocker_id = ? ORDER BY task_id", id)
+		if err != nil {
+			return err
+		}
+		for _, d := range found {
+			if !slices.Contains(ids, d) && !slices.Contains(dependents, d) {
+				dependents = append(dependents, d)
+			}
+		}
+	}
+	return s.track(q, "unlink", dependents, change)
+}
+
+// audit appends an entry to the audit log within q; nothing is recorded
+// if the change left the task as it was (or it exists neither before nor after it).
+func (s *SQLStore) audit(q execer, taskID, action string, before, after *Task) error {
+	b, err := auditState(before)
+	if err != nil {
+		return err
+	}
+	a, err := auditState(after)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(b, a) {
+		return nil
+	}
+	_, err = q.Exec(s.dialect.rebind(
+		"INSERT INTO audit_log (task_id, action, actor, at, before_state, after_state) VALUES (?, ?, ?, ?, ?, ?)"),
+		taskID, action, auditActor(s.actor), time.Now().UTC().Format(TimestampFormat), nullJSON(b), nullJSON(a))
+	return err
+}
+
+// snapshot returns a task with its tags, checklist and blockers as seen within q,
+// including a task in the trash; it is nil if the task does not exist.
+func (s *SQLStore) snapshot(q execer, id string) (*Task, error) {
+	task, err := scanTask(q.QueryRow(s.dialect.rebind("SELECT "+taskColumns+" FROM scheduler WHERE id = ?"), id), false)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	tasks := []*Task{task}
+	if err := s.loadTags(q, tasks); err != nil {
+		return nil, err
+	}
+	if err := s.loadChecklists(q, tasks); err != nil {
+		return nil, err
+	}
+	if err := s.loadBlockers(q, tasks); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// taskIDs returns the IDs selected by a query within q.
+func (s *SQLStore) taskIDs(q execer, query string, args ...any) ([]string, error) {
+	rows, err := q.Query(s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// nullJSON stores an empty snapshot as NULL.
+func nullJSON(data json.RawMessage) sql.NullString {
+	return sql.NullString{String: string(data), Valid: data != nil}
+}
@@ -0,0 +1,215 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ChecklistItem is a step of a multi-step task.
+//
+// Items are kept in the order set by ReorderChecklist (new items go last).
+// A task cannot be marked as done while any of its Required items is not Done;
+// when a repeating task moves to the next occurrence all its items are reset.
+type ChecklistItem struct {
+	ID       string `json:"id"`
+	TaskID   string `json:"task_id"`
+	Title    string `json:"title"`
+	Done     bool   `json:"done"`
+	Required bool   `json:"required"`
+}
+
+// checklistColumns lists checklist_items columns in the order expected by scanItem.
+const checklistColumns = "id, task_id, title, done, required"
+
+// activeItem restricts single-item statements to items of tasks that are not in the trash.
+const activeItem = " AND task_id IN (SELECT id FROM scheduler WHERE deleted_at IS NULL)"
+
+// scanItem reads a checklist item selected with checklistColumns.
+func scanItem(row rowScanner) (*ChecklistItem, error) {
+	item := &ChecklistItem{}
+	err := row.Scan(&item.ID, &item.TaskID, &item.Title, &item.Done, &item.Required)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Checklist returns the items of a task in their order.
+func (s *SQLStore) Checklist(taskID string) ([]*ChecklistItem, error) {
+	rows, err := s.db.Query(s.dialect.rebind(
+		"SELECT "+checklistColumns+" FROM checklist_items WHERE task_id = ? ORDER BY position, id"), taskID)
+	if err != nil {
+		return []*ChecklistItem{}, err
+	}
+	defer rows.Close()
+
+	items := []*ChecklistItem{}
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return []*ChecklistItem{}, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return []*ChecklistItem{}, err
+	}
+	return items, nil
+}
+
+// GetItem returns a single checklist item by id.
+func (s *SQLStore) GetItem(id string) (*ChecklistItem, error) {
+	item, err := scanItem(s.db.QueryRow(s.dialect.rebind("SELECT "+checklistColumns+" FROM checklist_items WHERE id = ?"+activeItem), id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("пункт не найден")
+		}
+		return nil, err
+	}
+	return item, nil
+}
+
+// AddItem appends an item to the checklist of item.TaskID and returns its ID.
+func (s *SQLStore) AddItem(item *ChecklistItem) (int64, error) {
+	var id int64
+	err := s.transaction(func(tx *sql.Tx) error {
+		return s.track(tx, "checklist", []string{item.TaskID}, func() error {
+			var err error
+			id, err = s.addItem(tx, item)
+			return err
+		})
+	})
+	return id, err
+}
+
+// addItem appends an item to a checklist within q.
+func (s *SQLStore) addItem(q execer, item *ChecklistItem) (int64, error) {
+	var position int
+	err := q.QueryRow(s.dialect.rebind("SELECT COALESCE(MAX(position), 0) FROM checklist_items WHERE task_id = ?"),
+		item.TaskID).Scan(&position)
+	if err != nil {
+		return 0, err
+	}
+	return s.dialect.insert(q, "INSERT INTO checklist_items (task_id, title, done, required, position) VALUES (?, ?, ?, ?, ?)",
+		item.TaskID, item.Title, item.Done, item.Required, position+1)
+}
+
+// UpdateItem replaces title, done and required of a checklist item.
+func (s *SQLStore) UpdateItem(item *ChecklistItem) error {
+	return s.changeItem(item.ID, "UPDATE checklist_items SET title = ?, done = ?, required = ? WHERE id = ?",
+		item.Title, item.Done, item.Required, item.ID)
+}
+
+// DeleteItem removes a checklist item by id.
+func (s *SQLStore) DeleteItem(id string) error {
+	return s.changeItem(id, "DELETE FROM checklist_items WHERE id = ?", id)
+}
+
+// changeItem runs a statement changing the item with the given id in a transaction,
+// recording the change of its task in the audit log.
+func (s *SQLStore) changeItem(id, query string, args ...any) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		var taskID string
+		err := tx.QueryRow(s.dialect.rebind("SELECT task_id FROM checklist_items WHERE id = ?"+activeItem), id).Scan(&taskID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("пункт не найден")
+			}
+			return err
+		}
+		return s.track(tx, "checklist", []string{taskID}, func() error {
+			res, err := tx.Exec(s.dialect.rebind(query), args...)
+			if err != nil {
+				return err
+			}
+			return itemAffected(res)
+		})
+	})
+}
+
+// ReorderChecklist sets the order of the task items; ids must list every item of the task once.
+func (s *SQLStore) ReorderChecklist(taskID string, ids []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.QueryRow(s.dialect.rebind("SELECT COUNT(*) FROM checklist_items WHERE task_id = ?"), taskID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count != len(ids) {
+		return fmt.Errorf("порядок должен содержать все пункты задачи (%d)", count)
+	}
+	err = s.track(tx, "checklist", []string{taskID}, func() error {
+		for i, id := range ids {
+			res, err := tx.Exec(s.dialect.rebind("UPDATE checklist_items SET position = ? WHERE id = ? AND task_id = ?"),
+				i+1, id, taskID)
+			if err != nil {
+				return err
+			}
+			if err := itemAffected(res); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// resetChecklist marks all items of a task as not done within q.
+func (s *SQLStore) resetChecklist(q execer, taskID string) error {
+	_, err := q.Exec(s.dialect.rebind("UPDATE checklist_items SET done = FALSE WHERE task_id = ?"), taskID)
+	return err
+}
+
+// loadChecklists fills Checklist of the tasks with a single query within q.
+func (s *SQLStore) loadChecklists(q execer, tasks []*Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	byID := make(map[string]*Task, len(tasks))
+	args := make([]any, 0, len(tasks))
+	for _, t := range tasks {
+		byID[t.ID] = t
+		args = append(args, t.ID)
+	}
+
+	query := "SELECT " + checklistColumns + " FROM checklist_items WHERE task_id IN (?" +
+		strings.Repeat(", ?", len(tasks)-1) + ") ORDER BY task_id, position, id"
+	rows, err := q.Query(s.dialect.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return err
+		}
+		if t, ok := byID[item.TaskID]; ok {
+			t.Checklist = append(t.Checklist, item)
+		}
+	}
+	return rows.Err()
+}
+
+// itemAffected reports a missing checklist item if the statement changed no rows.
+func itemAffected(res sql.Result) error {
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("пункт не найден")
+	}
+	return nil
+}
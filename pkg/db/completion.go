@@ -0,0 +1,135 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Completion records a task being done.
+//
+// Title and Date are snapshots of the task at the moment it was done:
+// Date is the date the task was scheduled for, CompletedAt is when it was done.
+// Completions are kept after the task itself is deleted.
+type Completion struct {
+	ID          string `json:"id"`
+	TaskID      string `json:"task_id"`
+	Title       string `json:"title"`
+	Date        string `json:"date"`
+	CompletedAt string `json:"completed_at"`
+}
+
+// completionColumns lists completions columns in the order expected by scanCompletions.
+const completionColumns = "id, task_id, title, date, completed_at"
+
+// Complete records a completion of the task at the given time and then moves the task
+// to the trash (next is empty) or to the next date like UpdateDate, in a single transaction;
+// anchor is the occurrence next was rolled from (see Task.Anchor).
+// A non-zero version must match the version of the task, otherwise nothing is changed and
+// an error with the text "версия задачи устарела" is returned. It returns the ID of the completion.
+func (s *SQLStore) Complete(id string, version int64, next, anchor string, at time.Time) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var completionID int64
+	err = s.track(tx, "done", []string{id}, func() error {
+		var title, date string
+		err := tx.QueryRow(s.dialect.rebind("SELECT title, date FROM scheduler WHERE id = ? AND deleted_at IS NULL"), id).Scan(&title, &date)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("задача не найдена")
+			}
+			return err
+		}
+
+		// The task is changed first: the version condition of the update makes
+		// a concurrent completion of the same occurrence fail as a whole.
+		err = s.trackDependents(tx, []string{id}, func() error {
+			if next == "" {
+				return s.trashTask(tx, id, version, at)
+			}
+			return s.updateDate(tx, next, anchor, id, version)
+		})
+		if err != nil {
+			return err
+		}
+		completionID, err = s.dialect.insert(tx, "INSERT INTO completions (task_id, title, date, completed_at) VALUES (?, ?, ?, ?)",
+			id, title, date, at.UTC().Format(TimestampFormat))
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return completionID, tx.Commit()
+}
+
+// DeleteCompletion removes a completion from the history.
+func (s *SQLStore) DeleteCompletion(id string) error {
+	res, err := s.db.Exec(s.dialect.rebind("DELETE FROM completions WHERE id = ?"), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("запись о выполнении не найдена")
+	}
+	return nil
+}
+
+// History returns the completions of a task, the latest first.
+func (s *SQLStore) History(taskID string) ([]*Completion, error) {
+	rows, err := s.db.Query(s.dialect.rebind(
+		"SELECT "+completionColumns+" FROM completions WHERE task_id = ? ORDER BY completed_at DESC, id DESC"), taskID)
+	if err != nil {
+		return []*Completion{}, err
+	}
+	return scanCompletions(rows)
+}
+
+// Completions returns the completions done in [from, to), the latest first.
+// A zero from/to means the range is not limited on that side.
+func (s *SQLStore) Completions(from, to time.Time) ([]*Completion, error) {
+	query := "SELECT " + completionColumns + " FROM completions WHERE 1=1"
+	var args []any
+	if !from.IsZero() {
+		query += " AND completed_at >= ?"
+		args = append(args, from.UTC().Format(TimestampFormat))
+	}
+	if !to.IsZero() {
+		query += " AND completed_at < ?"
+		args = append(args, to.UTC().Format(TimestampFormat))
+	}
+	query += " ORDER BY completed_at DESC, id DESC"
+
+	rows, err := s.db.Query(s.dialect.rebind(query), args...)
+	if err != nil {
+		return []*Completion{}, err
+	}
+	return scanCompletions(rows)
+}
+
+// scanCompletions reads and closes rows selected with completionColumns.
+func scanCompletions(rows *sql.Rows) ([]*Completion, error) {
+	defer rows.Close()
+
+	completions := []*Completion{}
+	for rows.Next() {
+		c := &Completion{}
+		if err := rows.Scan(&c.ID, &c.TaskID, &c.Title, &c.Date, &c.CompletedAt); err != nil {
+			return []*Completion{}, err
+		}
+		completions = append(completions, c)
+	}
+	if err := rows.Err(); err != nil {
+		return []*Completion{}, err
+	}
+	return completions, nil
+}
@@ -3,8 +3,6 @@ package db
 import (
 	"database/sql"
 	"fmt"
-	"os"
-	"time"
 
 	_ "modernc.org/sqlite"
 )
@@ -13,110 +11,51 @@ import (
 // It corresponds to YYYYMMDD.
 const DateFormat = "20060102"
 
+// TimestampFormat is the format of stored moments such as Completion.CompletedAt
+// and Task.DeletedAt: RFC 3339 in UTC, so that they sort as strings.
+const TimestampFormat = "2006-01-02T15:04:05Z"
+
 // DB is the shared database connection used by data access functions.
 var DB *sql.DB
 
-// schema is installed on first run (when the database file does not exist).
-const schema = `
-CREATE TABLE scheduler (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    date CHAR(8) NOT NULL DEFAULT '',
-    title VARCHAR(256) NOT NULL DEFAULT '',
-    comment TEXT NOT NULL DEFAULT '',
-    repeat VARCHAR(128) NOT NULL DEFAULT ''
-);
-CREATE INDEX idx_scheduler_date ON scheduler(date);
-`
+// current is the dialect of DB.
+var current dialect = DriverSQLite
 
-// Init opens SQLite database and installs schema on first run.
-func Init(dbFile string) error {
-	_, err := os.Stat(dbFile)
-	install := os.IsNotExist(err)
-	DB, err = sql.Open("sqlite", dbFile)
-	if err != nil {
-		return fmt.Errorf("ошибка при открытии базы данных: %w", err)
+// Init opens the database and applies pending schema migrations (see MigrateUp).
+//
+// driver is DriverSQLite or DriverPostgres; dsn is a file path for SQLite
+// or a connection string for Postgres.
+func Init(driver, dsn string) error {
+	if err := Open(driver, dsn); err != nil {
+		return err
 	}
-
-	if install {
-		_, err := DB.Exec(schema)
-		if err != nil {
-			return fmt.Errorf("ошибка при открытии базы данных: %w", err)
-		}
+	if _, err := MigrateUp(); err != nil {
+		return fmt.Errorf("ошибка миграции базы данных: %w", err)
 	}
 	return nil
 }
 
-// SearchTasks searches tasks by either:
-//   - a date in DD.MM.YYYY format, or
-//   - a substring match in title/comment.
-//
-// The `limit` parameter controls maximum number of returned items.
-func SearchTasks(search string, limit int) ([]*Task, error) {
-
-	date, err := time.Parse("02.01.2006", search)
-	if err == nil {
-		formatted := date.Format(DateFormat)
-		return TasksByDate(formatted, limit)
-	}
-
-	pattern := "%" + search + "%"
-	return TasksByPattern(pattern, limit)
-}
-
-// TasksByDate returns tasks scheduled on a specific date (YYYYMMDD).
-func TasksByDate(formatted string, limit int) ([]*Task, error) {
-	rows, err := DB.Query("SELECT id, date, title, comment, repeat FROM scheduler WHERE date = ? ORDER BY date LIMIT ?", formatted, limit)
+// Connect opens the database and applies pending schema migrations like Init,
+// but returns the connection instead of making it the shared DB, so that
+// several databases can be used at once (e.g. by tests).
+func Connect(driver, dsn string) (*sql.DB, error) {
+	conn, d, err := openDialect(driver, dsn)
 	if err != nil {
-		return []*Task{}, err
-	}
-
-	defer rows.Close()
-	tasks := []*Task{}
-
-	for rows.Next() {
-		task := &Task{}
-		err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
-
-		if err != nil {
-			return nil, err
-		}
-		tasks = append(tasks, task)
+		return nil, fmt.Errorf("ошибка при открытии базы данных: %w", err)
 	}
-	if err := rows.Err(); err != nil {
-		return []*Task{}, err
+	if _, err := (migrator{conn, d}).up(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ошибка миграции базы данных: %w", err)
 	}
-	if tasks == nil {
-		tasks = []*Task{}
-	}
-
-	return tasks, nil
+	return conn, nil
 }
 
-// TasksByPattern returns tasks where title or comment matches the given SQL LIKE pattern.
-func TasksByPattern(pattern string, limit int) ([]*Task, error) {
-	rows, err := DB.Query("SELECT id, date, title, comment, repeat FROM scheduler WHERE title LIKE ? OR comment LIKE ? ORDER BY date LIMIT ? ", pattern, pattern, limit)
+// Open opens the database without touching its schema.
+func Open(driver, dsn string) error {
+	conn, d, err := openDialect(driver, dsn)
 	if err != nil {
-		return []*Task{}, err
-	}
-
-	defer rows.Close()
-	tasks := []*Task{}
-
-	for rows.Next() {
-		task := &Task{}
-		err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
-
-		if err != nil {
-			return nil, err
-		}
-		tasks = append(tasks, task)
-	}
-	if err := rows.Err(); err != nil {
-		return []*Task{}, err
-	}
-	if tasks == nil {
-		tasks = []*Task{}
+		return fmt.Errorf("ошибка при открытии базы данных: %w", err)
 	}
-
-	return tasks, nil
+	DB, current = conn, d
+	return nil
 }
@@ -0,0 +1,162 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Link makes a task depend on a blocker: the task cannot be done until the blocker is.
+// Linking is refused with "цикл зависимостей" if the blocker already depends on the task,
+// directly or through other tasks (a task cannot block itself either).
+func (s *SQLStore) Link(taskID, blockerID string) error {
+	task, blocker, err := parseLink(taskID, blockerID)
+	if err != nil {
+		return err
+	}
+	if task == blocker {
+		return fmt.Errorf("цикл зависимостей")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = s.track(tx, "link", []string{taskID}, func() error {
+		return s.link(tx, task, blocker)
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// link inserts a dependency within q after checking that both tasks exist
+// and the link does not close a cycle (see Link).
+func (s *SQLStore) link(q execer, task, blocker int64) error {
+	var found int
+	err := q.QueryRow(s.dialect.rebind("SELECT COUNT(*) FROM scheduler WHERE id IN (?, ?) AND deleted_at IS NULL"), task, blocker).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if found != 2 {
+		return fmt.Errorf("задача не найдена")
+	}
+
+	// Walk the blockers of the blocker: reaching the task means the new link closes a cycle.
+	var cycle int
+	err = q.QueryRow(s.dialect.rebind(`WITH RECURSIVE chain (id) AS (
+			SELECT CAST(? AS BIGINT)
+			UNION
+			SELECT task_dependencies.blocker_id FROM task_dependencies JOIN chain ON task_dependencies.task_id = chain.id
+		) SELECT COUNT(*) FROM chain WHERE id = ?`), blocker, task).Scan(&cycle)
+	if err != nil {
+		return err
+	}
+	if cycle > 0 {
+		return fmt.Errorf("цикл зависимостей")
+	}
+
+	_, err = q.Exec(s.dialect.rebind("INSERT INTO task_dependencies (task_id, blocker_id) VALUES (?, ?) ON CONFLICT DO NOTHING"),
+		task, blocker)
+	return err
+}
+
+// Unlink removes a dependency between two tasks.
+func (s *SQLStore) Unlink(taskID, blockerID string) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		return s.track(tx, "unlink", []string{taskID}, func() error {
+			res, err := tx.Exec(s.dialect.rebind("DELETE FROM task_dependencies WHERE task_id = ? AND blocker_id = ?"),
+				taskID, blockerID)
+			if err != nil {
+				return err
+			}
+
+			rowsAffected, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if rowsAffected == 0 {
+				return fmt.Errorf("зависимость не найдена")
+			}
+			return nil
+		})
+	})
+}
+
+// Dependents returns IDs of the tasks blocked by the task, in ascending order.
+func (s *SQLStore) Dependents(taskID string) ([]string, error) {
+	rows, err := s.db.Query(s.dialect.rebind("SELECT task_id FROM task_dependencies WHERE blocker_id = ? ORDER BY task_id"), taskID)
+	if err != nil {
+		return []string{}, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return []string{}, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return []string{}, err
+	}
+	return ids, nil
+}
+
+// unblockDependents removes the links of tasks blocked by the task within q.
+func (s *SQLStore) unblockDependents(q execer, taskID string) error {
+	_, err := q.Exec(s.dialect.rebind("DELETE FROM task_dependencies WHERE blocker_id = ?"), taskID)
+	return err
+}
+
+// loadBlockers fills BlockedBy and Blocked of the tasks with a single query within q.
+func (s *SQLStore) loadBlockers(q execer, tasks []*Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	byID := make(map[string]*Task, len(tasks))
+	args := make([]any, 0, len(tasks))
+	for _, t := range tasks {
+		byID[t.ID] = t
+		args = append(args, t.ID)
+	}
+
+	query := "SELECT task_id, blocker_id FROM task_dependencies WHERE task_id IN (?" +
+		strings.Repeat(", ?", len(tasks)-1) + ") ORDER BY task_id, blocker_id"
+	rows, err := q.Query(s.dialect.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, blocker string
+		if err := rows.Scan(&id, &blocker); err != nil {
+			return err
+		}
+		if t, ok := byID[id]; ok {
+			t.BlockedBy = append(t.BlockedBy, blocker)
+			t.Blocked = true
+		}
+	}
+	return rows.Err()
+}
+
+// parseLink converts task IDs of a dependency to numbers.
+func parseLink(taskID, blockerID string) (int64, int64, error) {
+	task, err := strconv.ParseInt(taskID, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("задача не найдена")
+	}
+	blocker, err := strconv.ParseInt(blockerID, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("задача не найдена")
+	}
+	return task, blocker, nil
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Supported values of TODO_DB_DRIVER.
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// sqlDrivers maps storage drivers to database/sql driver names.
+// The Postgres driver is linked only in builds with the "postgres" tag (see postgres.go).
+var sqlDrivers = map[string]string{
+	DriverSQLite:   "sqlite",
+	DriverPostgres: "pgx",
+}
+
+// dialect hides SQL differences between the supported databases.
+//
+// Queries are written with "?" placeholders and LIKE, as for SQLite;
+// Postgres gets them rewritten to "$1, $2, ..." and ILIKE.
+type dialect string
+
+// rebind rewrites "?" placeholders for the dialect.
+// Question marks inside single-quoted literals are kept.
+func (d dialect) rebind(query string) string {
+	if d != DriverPostgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// like returns the case-insensitive pattern operator.
+// SQLite LIKE already ignores case of ASCII letters.
+func (d dialect) like() string {
+	if d == DriverPostgres {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
+
+// execer is implemented by *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// insert runs an INSERT and returns the ID of the new row.
+// Postgres does not support LastInsertId, so "RETURNING id" is used there.
+func (d dialect) insert(q execer, query string, args ...any) (int64, error) {
+	var id int64
+	if d == DriverPostgres {
+		err := q.QueryRow(d.rebind(query)+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+
+	res, err := q.Exec(query, args...)
+	if err == nil {
+		id, err = res.LastInsertId()
+	}
+	return id, err
+}
+
+// openDialect validates the driver and opens a connection pool for it.
+func openDialect(driver, dsn string) (*sql.DB, dialect, error) {
+	name, ok := sqlDrivers[driver]
+	if !ok {
+		return nil, "", fmt.Errorf("неизвестный драйвер базы данных %q (допустимо sqlite или postgres)", driver)
+	}
+	if !driverLinked(name) {
+		return nil, "", fmt.Errorf("драйвер %q не включён в сборку (соберите с -tags %s)", driver, driver)
+	}
+	conn, err := sql.Open(name, dsn)
+	if err != nil {
+		return nil, "", err
+	}
+	return conn, dialect(driver), nil
+}
+
+// driverLinked reports whether a database/sql driver is registered.
+func driverLinked(name string) bool {
+	for _, d := range sql.Drivers() {
+		if d == name {
+			return true
+		}
+	}
+	return false
+}
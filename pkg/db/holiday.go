@@ -0,0 +1,139 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Holiday is a non-working day used by business-day repeat rules and date rolling.
+//
+// Date uses DateFormat (YYYYMMDD) and is unique.
+type Holiday struct {
+	ID    string `json:"id"`
+	Date  string `json:"date"`
+	Title string `json:"title"`
+}
+
+// AddHoliday inserts a new holiday and returns its auto-generated database ID.
+func (s *SQLStore) AddHoliday(holiday *Holiday) (int64, error) {
+	return s.dialect.insert(s.db, "INSERT INTO holidays (date, title) VALUES (?, ?)", holiday.Date, holiday.Title)
+}
+
+// ImportHolidays inserts holidays in a single transaction.
+// Existing dates keep their records but get the new title.
+func (s *SQLStore) ImportHolidays(holidays []*Holiday) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		for _, h := range holidays {
+			_, err := tx.Exec(s.dialect.rebind(
+				"INSERT INTO holidays (date, title) VALUES (?, ?) ON CONFLICT(date) DO UPDATE SET title = excluded.title"),
+				h.Date, h.Title,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Holidays returns holidays ordered by date.
+// Empty from/to (YYYYMMDD) mean the range is not limited on that side.
+func (s *SQLStore) Holidays(from, to string) ([]*Holiday, error) {
+	query := "SELECT id, date, title FROM holidays WHERE 1=1"
+	var args []any
+	if from != "" {
+		query += " AND date >= ?"
+		args = append(args, from)
+	}
+	if to != "" {
+		query += " AND date <= ?"
+		args = append(args, to)
+	}
+	query += " ORDER BY date"
+
+	rows, err := s.db.Query(s.dialect.rebind(query), args...)
+	if err != nil {
+		return []*Holiday{}, err
+	}
+	defer rows.Close()
+
+	holidays := []*Holiday{}
+	for rows.Next() {
+		h := &Holiday{}
+		if err := rows.Scan(&h.ID, &h.Date, &h.Title); err != nil {
+			return nil, err
+		}
+		holidays = append(holidays, h)
+	}
+	if err := rows.Err(); err != nil {
+		return []*Holiday{}, err
+	}
+	return holidays, nil
+}
+
+// GetHoliday returns a single holiday by id.
+func (s *SQLStore) GetHoliday(id string) (*Holiday, error) {
+	h := &Holiday{}
+	err := s.db.QueryRow(s.dialect.rebind("SELECT id, date, title FROM holidays WHERE id = ?"), id).Scan(&h.ID, &h.Date, &h.Title)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("праздник не найден")
+		}
+		return nil, err
+	}
+	return h, nil
+}
+
+// UpdateHoliday updates an existing holiday by id.
+func (s *SQLStore) UpdateHoliday(holiday *Holiday) error {
+	res, err := s.db.Exec(s.dialect.rebind("UPDATE holidays SET date=?, title=? WHERE id=?"), holiday.Date, holiday.Title, holiday.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("праздник не найден")
+	}
+	return nil
+}
+
+// DeleteHoliday removes a holiday by id.
+func (s *SQLStore) DeleteHoliday(id string) error {
+	res, err := s.db.Exec(s.dialect.rebind("DELETE FROM holidays WHERE id=?"), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("праздник не найден")
+	}
+	return nil
+}
+
+// HolidayDates returns the set of all holiday dates (YYYYMMDD).
+func (s *SQLStore) HolidayDates() (map[string]bool, error) {
+	rows, err := s.db.Query("SELECT date FROM holidays")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dates := map[string]bool{}
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			return nil, err
+		}
+		dates[date] = true
+	}
+	return dates, rows.Err()
+}
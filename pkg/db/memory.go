@@ -0,0 +1,1212 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a thread-safe in-memory TaskStore.
+// It is meant for tests and ephemeral runs: data is lost when the process exits.
+// Views returned by As share the data and differ only in the actor recorded in the audit log.
+type MemoryStore struct {
+	*memoryData
+	actor string
+}
+
+// memoryData is the state shared by a MemoryStore and its views.
+type memoryData struct {
+	mu               sync.RWMutex
+	tasks            map[int64]Task
+	nextID           int64
+	tags             map[int64]string
+	nextTagID        int64
+	projects         map[int64]Project
+	nextProjectID    int64
+	items            map[string][]ChecklistItem
+	nextItemID       int64
+	blockers         map[string][]string
+	completions      []Completion
+	nextCompletionID int64
+	audit            []AuditEntry
+	holidays         map[int64]Holiday
+	nextHolidayID    int64
+}
+
+// NewMemoryStore returns an empty in-memory TaskStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{memoryData: &memoryData{
+		tasks: map[int64]Task{}, nextID: 1,
+		tags: map[int64]string{}, nextTagID: 1,
+		projects: map[int64]Project{}, nextProjectID: 1,
+		items: map[string][]ChecklistItem{}, nextItemID: 1,
+		blockers: map[string][]string{}, nextCompletionID: 1,
+		holidays: map[int64]Holiday{}, nextHolidayID: 1,
+	}}
+}
+
+// Add stores a copy of the task under a new ID.
+func (s *MemoryStore) Add(task *Task) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID
+	s.nextID++
+	t := *task
+	t.ID = strconv.FormatInt(id, 10)
+	t.Tags = s.useTags(task.Tags)
+	t.Checklist = nil
+	t.BlockedBy, t.Blocked = nil, false
+	t.DeletedAt = ""
+	t.Version = 1
+	s.tasks[id] = t
+	for _, item := range task.Checklist {
+		item.TaskID = t.ID
+		s.addItem(item)
+	}
+	s.record(t.ID, "add", nil, s.snapshot(t.ID))
+	return id, nil
+}
+
+// Get returns a copy of the task by id.
+func (s *MemoryStore) Get(id string) (*Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.lookup(id)
+	if !ok {
+		return nil, fmt.Errorf("task not found")
+	}
+	t.Tags = slices.Clone(t.Tags)
+	t.Checklist = s.checklist(t.ID)
+	s.fillBlockers(&t)
+	return &t, nil
+}
+
+// Update replaces an existing task unless task.Version is set and differs from the stored one.
+func (s *MemoryStore) Update(task *Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.lookup(task.ID)
+	if !ok {
+		return fmt.Errorf("задача не найдена")
+	}
+	if task.Version != 0 && task.Version != old.Version {
+		return fmt.Errorf("версия задачи устарела")
+	}
+	s.track("update", []string{old.ID}, func() {
+		t := *task
+		t.ID = old.ID
+		t.Tags = old.Tags
+		t.Checklist = nil
+		t.BlockedBy, t.Blocked = nil, false
+		t.DeletedAt = ""
+		t.Version = old.Version + 1
+		if task.Tags != nil {
+			t.Tags = s.useTags(task.Tags)
+		}
+		key, _ := strconv.ParseInt(old.ID, 10, 64)
+		s.tasks[key] = t
+	})
+	task.Version = old.Version + 1
+	return nil
+}
+
+// Delete moves a task to the trash.
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.lookup(id)
+	if !ok {
+		return fmt.Errorf("задача не найдена")
+	}
+	s.track("delete", []string{t.ID}, func() {
+		s.track("unlink", s.dependents(t.ID), func() { s.trashTask(t, time.Now()) })
+	})
+	return nil
+}
+
+// List returns a page of tasks matching the filter.
+func (s *MemoryStore) List(filter Filter, page Page) ([]*Task, error) {
+	search, err := filter.search()
+	if err == nil && search != nil {
+		err = search.checkPortable()
+	}
+	if err != nil {
+		return []*Task{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var found []*Task
+	for _, t := range s.tasks {
+		if t.DeletedAt != "" || filter.hidesArchived() && s.archived(t.ProjectID) {
+			continue
+		}
+		if filter.match(&t, search) && page.after(&t) {
+			t.Tags = slices.Clone(t.Tags)
+			t.Checklist = s.checklist(t.ID)
+			s.fillBlockers(&t)
+			found = append(found, &t)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		ki, ii := page.position(found[i])
+		kj, ij := page.position(found[j])
+		return page.less(ki, ii, kj, ij)
+	})
+
+	if len(found) > page.Limit {
+		found = found[:page.Limit]
+	}
+	return append([]*Task{}, found...), nil
+}
+
+// UpdateDate moves a task to the next date, consumes one occurrence of repeat_count (if set),
+// resets the task checklist and unblocks the tasks depending on it.
+func (s *MemoryStore) UpdateDate(next string, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.lookup(id)
+	if !ok {
+		return fmt.Errorf("задача не найдена")
+	}
+	s.track("reschedule", []string{t.ID}, func() {
+		s.track("unlink", s.dependents(t.ID), func() { s.updateDate(t, next, "") })
+	})
+	return nil
+}
+
+// updateDate moves a task to the next date rolled from anchor (see UpdateDate and Task.Anchor).
+// The caller must hold the lock.
+func (s *MemoryStore) updateDate(t Task, next, anchor string) {
+	for i := range s.items[t.ID] {
+		s.items[t.ID][i].Done = false
+	}
+	s.unblockDependents(t.ID)
+	t.Date = next
+	t.Anchor = anchor
+	if t.RepeatCount > 0 {
+		t.RepeatCount--
+	}
+	t.Version++
+	key, _ := strconv.ParseInt(t.ID, 10, 64)
+	s.tasks[key] = t
+}
+
+// lookup finds a task that is not in the trash by its string id. The caller must hold the lock.
+func (s *MemoryStore) lookup(id string) (Task, bool) {
+	t, ok := s.find(id)
+	return t, ok && t.DeletedAt == ""
+}
+
+// find finds a task by its string id, including tasks in the trash. The caller must hold the lock.
+func (s *MemoryStore) find(id string) (Task, bool) {
+	key, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return Task{}, false
+	}
+	t, ok := s.tasks[key]
+	return t, ok
+}
+
+// Tags returns all tags ordered by name, with the number of tasks (not in the trash) for each.
+func (s *MemoryStore) Tags() ([]*Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := map[string]int{}
+	for _, t := range s.tasks {
+		if t.DeletedAt != "" {
+			continue
+		}
+		for _, name := range t.Tags {
+			count[name]++
+		}
+	}
+	tags := []*Tag{}
+	for id, name := range s.tags {
+		tags = append(tags, &Tag{ID: strconv.FormatInt(id, 10), Name: name, Tasks: count[name]})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+// AddTag creates a tag and returns its ID.
+func (s *MemoryStore) AddTag(name string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tagID(name); ok {
+		return 0, fmt.Errorf("тег уже существует")
+	}
+	id := s.nextTagID
+	s.nextTagID++
+	s.tags[id] = name
+	return id, nil
+}
+
+// RenameTag changes the name of a tag in the dictionary and in all tasks.
+func (s *MemoryStore) RenameTag(tag *Tag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := strconv.ParseInt(tag.ID, 10, 64)
+	old, ok := s.tags[key]
+	if err != nil || !ok {
+		return fmt.Errorf("тег не найден")
+	}
+	if id, ok := s.tagID(tag.Name); ok && id != key {
+		return fmt.Errorf("тег уже существует")
+	}
+	s.track("tag", s.tagged(old), func() {
+		s.tags[key] = tag.Name
+		s.replaceTag(old, tag.Name)
+	})
+	return nil
+}
+
+// DeleteTag removes a tag from all tasks and deletes it.
+func (s *MemoryStore) DeleteTag(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := strconv.ParseInt(id, 10, 64)
+	name, ok := s.tags[key]
+	if err != nil || !ok {
+		return fmt.Errorf("тег не найден")
+	}
+	s.track("tag", s.tagged(name), func() {
+		delete(s.tags, key)
+		s.replaceTag(name, "")
+	})
+	return nil
+}
+
+// useTags registers missing tags and returns a sorted copy of the names. The caller must hold the lock.
+func (s *MemoryStore) useTags(names []string) []string {
+	if len(names) == 0 {
+		return nil
+	}
+	for _, name := range names {
+		if _, ok := s.tagID(name); !ok {
+			s.tags[s.nextTagID] = name
+			s.nextTagID++
+		}
+	}
+	names = slices.Clone(names)
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// replaceTag renames a tag in all tasks, or removes it if name is empty. The caller must hold the lock.
+func (s *MemoryStore) replaceTag(old, name string) {
+	for key, t := range s.tasks {
+		i := slices.Index(t.Tags, old)
+		if i < 0 {
+			continue
+		}
+		tags := slices.Delete(slices.Clone(t.Tags), i, i+1)
+		if name != "" {
+			tags = append(tags, name)
+			slices.Sort(tags)
+		}
+		if len(tags) == 0 {
+			tags = nil
+		}
+		t.Tags = tags
+		t.Version++
+		s.tasks[key] = t
+	}
+}
+
+// tagged returns IDs of the tasks with a tag, including tasks in the trash, in ascending order.
+// The caller must hold the lock.
+func (s *MemoryStore) tagged(name string) []string {
+	var ids []string
+	for _, t := range s.tasks {
+		if slices.Contains(t.Tags, name) {
+			ids = append(ids, t.ID)
+		}
+	}
+	sortIDs(ids)
+	return ids
+}
+
+// tagID finds a tag by name. The caller must hold the lock.
+func (s *MemoryStore) tagID(name string) (int64, bool) {
+	for id, n := range s.tags {
+		if n == name {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// Projects returns projects ordered by name; archived ones are included only if archived is set.
+func (s *MemoryStore) Projects(archived bool) ([]*Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	projects := []*Project{}
+	for _, p := range s.projects {
+		if p.Archived && !archived {
+			continue
+		}
+		p.Tasks = s.projectTasks(p.ID)
+		projects = append(projects, &p)
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].Name != projects[j].Name {
+			return projects[i].Name < projects[j].Name
+		}
+		ki, _ := strconv.ParseInt(projects[i].ID, 10, 64)
+		kj, _ := strconv.ParseInt(projects[j].ID, 10, 64)
+		return ki < kj
+	})
+	return projects, nil
+}
+
+// GetProject returns a copy of the project by id.
+func (s *MemoryStore) GetProject(id string) (*Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.lookupProject(id)
+	if !ok {
+		return nil, fmt.Errorf("проект не найден")
+	}
+	p.Tasks = s.projectTasks(p.ID)
+	return &p, nil
+}
+
+// AddProject stores a copy of the project under a new ID.
+func (s *MemoryStore) AddProject(project *Project) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextProjectID
+	s.nextProjectID++
+	p := *project
+	p.ID = strconv.FormatInt(id, 10)
+	p.Tasks = 0
+	s.projects[id] = p
+	return id, nil
+}
+
+// UpdateProject replaces name, color and the archived flag of a project.
+func (s *MemoryStore) UpdateProject(project *Project) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.lookupProject(project.ID)
+	if !ok {
+		return fmt.Errorf("проект не найден")
+	}
+	p.Name, p.Color, p.Archived = project.Name, project.Color, project.Archived
+	key, _ := strconv.ParseInt(p.ID, 10, 64)
+	s.projects[key] = p
+	return nil
+}
+
+// DeleteProject deletes a project and moves its tasks to the trash or to another project.
+func (s *MemoryStore) DeleteProject(id string, cascade bool, moveTo string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.lookupProject(id)
+	if !ok {
+		return fmt.Errorf("проект не найден")
+	}
+	var ids []string
+	for _, t := range s.tasks {
+		if t.ProjectID == p.ID {
+			ids = append(ids, t.ID)
+		}
+	}
+	sortIDs(ids)
+	action := "move"
+	if cascade {
+		action = "delete"
+	}
+	s.track(action, ids, func() {
+		s.track("unlink", s.dependents(ids...), func() { s.moveProjectTasks(p.ID, cascade, moveTo) })
+	})
+	key, _ := strconv.ParseInt(p.ID, 10, 64)
+	delete(s.projects, key)
+	return nil
+}
+
+// moveProjectTasks moves the tasks of a project to the trash (cascade) or to the project moveTo.
+// The caller must hold the lock.
+func (s *MemoryStore) moveProjectTasks(projectID string, cascade bool, moveTo string) {
+	for key, t := range s.tasks {
+		if t.ProjectID != projectID {
+			continue
+		}
+		if cascade && t.DeletedAt == "" {
+			s.trashTask(t, time.Now())
+			t = s.tasks[key]
+		} else {
+			t.Version++
+		}
+		t.ProjectID = moveTo
+		if cascade {
+			t.ProjectID = ""
+		}
+		s.tasks[key] = t
+	}
+}
+
+// MoveTask moves a task to a project ("" for no project) and returns its new version.
+// A non-zero version must match the task version.
+func (s *MemoryStore) MoveTask(id, projectID string, version int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.lookup(id)
+	if !ok {
+		return 0, fmt.Errorf("задача не найдена")
+	}
+	if version != 0 && t.Version != version {
+		return 0, fmt.Errorf("версия задачи устарела")
+	}
+	s.track("move", []string{t.ID}, func() {
+		t.ProjectID = projectID
+		t.Version++
+		key, _ := strconv.ParseInt(t.ID, 10, 64)
+		s.tasks[key] = t
+	})
+	return t.Version, nil
+}
+
+// lookupProject finds a project by its string id. The caller must hold the lock.
+func (s *MemoryStore) lookupProject(id string) (Project, bool) {
+	key, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return Project{}, false
+	}
+	p, ok := s.projects[key]
+	return p, ok
+}
+
+// projectTasks counts the tasks of a project. The caller must hold the lock.
+func (s *MemoryStore) projectTasks(id string) int {
+	n := 0
+	for _, t := range s.tasks {
+		if t.ProjectID == id && t.DeletedAt == "" {
+			n++
+		}
+	}
+	return n
+}
+
+// Holidays returns holidays in the optional [from, to] range ordered by date.
+func (s *MemoryStore) Holidays(from, to string) ([]*Holiday, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	holidays := []*Holiday{}
+	for _, h := range s.holidays {
+		if (from != "" && h.Date < from) || (to != "" && h.Date > to) {
+			continue
+		}
+		holidays = append(holidays, &h)
+	}
+	sort.Slice(holidays, func(i, j int) bool { return holidays[i].Date < holidays[j].Date })
+	return holidays, nil
+}
+
+// GetHoliday returns a copy of the holiday by id.
+func (s *MemoryStore) GetHoliday(id string) (*Holiday, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	h, ok := s.lookupHoliday(id)
+	if !ok {
+		return nil, fmt.Errorf("праздник не найден")
+	}
+	return &h, nil
+}
+
+// AddHoliday stores a copy of the holiday under a new ID; the date must not be taken.
+func (s *MemoryStore) AddHoliday(holiday *Holiday) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.holidayByDate(holiday.Date); ok {
+		return 0, fmt.Errorf("праздник на дату %s уже есть", holiday.Date)
+	}
+	return s.addHoliday(holiday), nil
+}
+
+// UpdateHoliday replaces the date and title of a holiday; the new date must not be taken by another one.
+func (s *MemoryStore) UpdateHoliday(holiday *Holiday) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, ok := s.lookupHoliday(holiday.ID)
+	if !ok {
+		return fmt.Errorf("праздник не найден")
+	}
+	if other, ok := s.holidayByDate(holiday.Date); ok && other != h.ID {
+		return fmt.Errorf("праздник на дату %s уже есть", holiday.Date)
+	}
+	h.Date, h.Title = holiday.Date, holiday.Title
+	key, _ := strconv.ParseInt(h.ID, 10, 64)
+	s.holidays[key] = h
+	return nil
+}
+
+// DeleteHoliday removes a holiday by id.
+func (s *MemoryStore) DeleteHoliday(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, ok := s.lookupHoliday(id)
+	if !ok {
+		return fmt.Errorf("праздник не найден")
+	}
+	key, _ := strconv.ParseInt(h.ID, 10, 64)
+	delete(s.holidays, key)
+	return nil
+}
+
+// ImportHolidays adds holidays; existing dates keep their records but get the new title.
+func (s *MemoryStore) ImportHolidays(holidays []*Holiday) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, holiday := range holidays {
+		id, ok := s.holidayByDate(holiday.Date)
+		if !ok {
+			s.addHoliday(holiday)
+			continue
+		}
+		h, _ := s.lookupHoliday(id)
+		h.Title = holiday.Title
+		key, _ := strconv.ParseInt(id, 10, 64)
+		s.holidays[key] = h
+	}
+	return nil
+}
+
+// HolidayDates returns the set of all holiday dates (YYYYMMDD).
+func (s *MemoryStore) HolidayDates() (map[string]bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dates := make(map[string]bool, len(s.holidays))
+	for _, h := range s.holidays {
+		dates[h.Date] = true
+	}
+	return dates, nil
+}
+
+// addHoliday stores a copy of the holiday under a new ID. The caller must hold the lock.
+func (s *MemoryStore) addHoliday(holiday *Holiday) int64 {
+	id := s.nextHolidayID
+	s.nextHolidayID++
+	h := *holiday
+	h.ID = strconv.FormatInt(id, 10)
+	s.holidays[id] = h
+	return id
+}
+
+// lookupHoliday finds a holiday by its string id. The caller must hold the lock.
+func (s *MemoryStore) lookupHoliday(id string) (Holiday, bool) {
+	key, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return Holiday{}, false
+	}
+	h, ok := s.holidays[key]
+	return h, ok
+}
+
+// holidayByDate returns the id of the holiday on a date. The caller must hold the lock.
+func (s *MemoryStore) holidayByDate(date string) (string, bool) {
+	for _, h := range s.holidays {
+		if h.Date == date {
+			return h.ID, true
+		}
+	}
+	return "", false
+}
+
+// archived reports whether the task project is archived. The caller must hold the lock.
+func (s *MemoryStore) archived(projectID string) bool {
+	p, ok := s.lookupProject(projectID)
+	return ok && p.Archived
+}
+
+// Checklist returns copies of the task items in their order.
+func (s *MemoryStore) Checklist(taskID string) ([]*ChecklistItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := s.checklist(taskID)
+	if items == nil {
+		items = []*ChecklistItem{}
+	}
+	return items, nil
+}
+
+// GetItem returns a copy of a checklist item by id.
+func (s *MemoryStore) GetItem(id string) (*ChecklistItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	taskID, i, ok := s.lookupItem(id)
+	if !ok {
+		return nil, fmt.Errorf("пункт не найден")
+	}
+	item := s.items[taskID][i]
+	return &item, nil
+}
+
+// AddItem appends an item to the checklist of item.TaskID.
+func (s *MemoryStore) AddItem(item *ChecklistItem) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var id int64
+	s.track("checklist", []string{item.TaskID}, func() { id = s.addItem(item) })
+	return id, nil
+}
+
+// UpdateItem replaces title, done and required of a checklist item.
+func (s *MemoryStore) UpdateItem(item *ChecklistItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	taskID, i, ok := s.lookupItem(item.ID)
+	if !ok {
+		return fmt.Errorf("пункт не найден")
+	}
+	s.track("checklist", []string{taskID}, func() {
+		stored := &s.items[taskID][i]
+		stored.Title, stored.Done, stored.Required = item.Title, item.Done, item.Required
+	})
+	return nil
+}
+
+// DeleteItem removes a checklist item by id.
+func (s *MemoryStore) DeleteItem(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	taskID, i, ok := s.lookupItem(id)
+	if !ok {
+		return fmt.Errorf("пункт не найден")
+	}
+	s.track("checklist", []string{taskID}, func() {
+		s.items[taskID] = slices.Delete(s.items[taskID], i, i+1)
+	})
+	return nil
+}
+
+// ReorderChecklist sets the order of the task items.
+func (s *MemoryStore) ReorderChecklist(taskID string, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := s.items[taskID]
+	if len(items) != len(ids) {
+		return fmt.Errorf("порядок должен содержать все пункты задачи (%d)", len(items))
+	}
+	ordered := make([]ChecklistItem, 0, len(items))
+	for _, id := range ids {
+		i := slices.IndexFunc(items, func(item ChecklistItem) bool { return item.ID == id })
+		if i < 0 {
+			return fmt.Errorf("пункт не найден")
+		}
+		ordered = append(ordered, items[i])
+	}
+	s.track("checklist", []string{taskID}, func() { s.items[taskID] = ordered })
+	return nil
+}
+
+// addItem stores a copy of the item under a new ID. The caller must hold the lock.
+func (s *MemoryStore) addItem(item *ChecklistItem) int64 {
+	id := s.nextItemID
+	s.nextItemID++
+	stored := *item
+	stored.ID = strconv.FormatInt(id, 10)
+	s.items[item.TaskID] = append(s.items[item.TaskID], stored)
+	return id
+}
+
+// checklist returns copies of the task items, or nil if there are none. The caller must hold the lock.
+func (s *MemoryStore) checklist(taskID string) []*ChecklistItem {
+	var items []*ChecklistItem
+	for _, item := range s.items[taskID] {
+		items = append(items, &item)
+	}
+	return items
+}
+
+// lookupItem finds a checklist item of a task not in the trash by id. The caller must hold the lock.
+func (s *MemoryStore) lookupItem(id string) (string, int, bool) {
+	for taskID, items := range s.items {
+		for i, item := range items {
+			if item.ID == id {
+				_, ok := s.lookup(taskID)
+				return taskID, i, ok
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// Link makes a task depend on a blocker unless that closes a cycle.
+func (s *MemoryStore) Link(taskID, blockerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.lookup(taskID)
+	if !ok {
+		return fmt.Errorf("задача не найдена")
+	}
+	var err error
+	s.track("link", []string{task.ID}, func() { err = s.link(task.ID, blockerID) })
+	return err
+}
+
+// link makes a task depend on a blocker after checking that both tasks exist
+// and the link does not close a cycle (see Link). The caller must hold the lock.
+func (s *MemoryStore) link(taskID, blockerID string) error {
+	task, ok := s.lookup(taskID)
+	blocker, found := s.lookup(blockerID)
+	if !ok || !found {
+		return fmt.Errorf("задача не найдена")
+	}
+	if task.ID == blocker.ID {
+		return fmt.Errorf("цикл зависимостей")
+	}
+
+	// Walk the blockers of the blocker: reaching the task means the new link closes a cycle.
+	seen := map[string]bool{}
+	queue := []string{blocker.ID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if id == task.ID {
+			return fmt.Errorf("цикл зависимостей")
+		}
+		if !seen[id] {
+			seen[id] = true
+			queue = append(queue, s.blockers[id]...)
+		}
+	}
+
+	if !slices.Contains(s.blockers[task.ID], blocker.ID) {
+		s.blockers[task.ID] = append(s.blockers[task.ID], blocker.ID)
+		sortIDs(s.blockers[task.ID])
+	}
+	return nil
+}
+
+// Unlink removes a dependency between two tasks.
+func (s *MemoryStore) Unlink(taskID, blockerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.Index(s.blockers[taskID], blockerID)
+	if i < 0 {
+		return fmt.Errorf("зависимость не найдена")
+	}
+	s.track("unlink", []string{taskID}, func() {
+		s.blockers[taskID] = slices.Delete(s.blockers[taskID], i, i+1)
+		if len(s.blockers[taskID]) == 0 {
+			delete(s.blockers, taskID)
+		}
+	})
+	return nil
+}
+
+// Dependents returns IDs of the tasks blocked by the task, in ascending order.
+func (s *MemoryStore) Dependents(taskID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := []string{}
+	for id, blockers := range s.blockers {
+		if slices.Contains(blockers, taskID) {
+			ids = append(ids, id)
+		}
+	}
+	sortIDs(ids)
+	return ids, nil
+}
+
+// dependents returns IDs of the tasks blocked by any of the tasks ids and not among them,
+// in ascending order. The caller must hold the lock.
+func (s *MemoryStore) dependents(ids ...string) []string {
+	var found []string
+	for id, blockers := range s.blockers {
+		if slices.Contains(ids, id) {
+			continue
+		}
+		for _, b := range blockers {
+			if slices.Contains(ids, b) {
+				found = append(found, id)
+				break
+			}
+		}
+	}
+	sortIDs(found)
+	return found
+}
+
+// fillBlockers sets BlockedBy and Blocked of a task copy. The caller must hold the lock.
+func (s *MemoryStore) fillBlockers(t *Task) {
+	t.BlockedBy = slices.Clone(s.blockers[t.ID])
+	t.Blocked = len(t.BlockedBy) > 0
+}
+
+// unblockDependents removes the links of tasks blocked by the task. The caller must hold the lock.
+func (s *MemoryStore) unblockDependents(taskID string) {
+	for id, blockers := range s.blockers {
+		blockers = slices.DeleteFunc(blockers, func(b string) bool { return b == taskID })
+		if len(blockers) == 0 {
+			delete(s.blockers, id)
+		} else {
+			s.blockers[id] = blockers
+		}
+	}
+}
+
+// removeTask deletes a task with its checklist and dependencies. The caller must hold the lock.
+func (s *MemoryStore) removeTask(id string) {
+	key, _ := strconv.ParseInt(id, 10, 64)
+	delete(s.tasks, key)
+	delete(s.items, id)
+	delete(s.blockers, id)
+	s.unblockDependents(id)
+}
+
+// sortIDs sorts numeric string IDs in ascending order.
+func sortIDs(ids []string) {
+	sort.Slice(ids, func(i, j int) bool {
+		a, _ := strconv.ParseInt(ids[i], 10, 64)
+		b, _ := strconv.ParseInt(ids[j], 10, 64)
+		return a < b
+	})
+}
+
+// Complete records a completion of the task and then moves it to the trash (next is empty)
+// or to the next date rolled from anchor like UpdateDate; a non-zero version must match the task version.
+// It returns the ID of the completion.
+func (s *MemoryStore) Complete(id string, version int64, next, anchor string, at time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.lookup(id)
+	if !ok {
+		return 0, fmt.Errorf("задача не найдена")
+	}
+	if version != 0 && t.Version != version {
+		return 0, fmt.Errorf("версия задачи устарела")
+	}
+	completionID := s.nextCompletionID
+	s.nextCompletionID++
+	s.track("done", []string{t.ID}, func() {
+		s.completions = append(s.completions, Completion{
+			ID:          strconv.FormatInt(completionID, 10),
+			TaskID:      t.ID,
+			Title:       t.Title,
+			Date:        t.Date,
+			CompletedAt: at.UTC().Format(TimestampFormat),
+		})
+
+		s.track("unlink", s.dependents(t.ID), func() {
+			if next == "" {
+				s.trashTask(t, at)
+			} else {
+				s.updateDate(t, next, anchor)
+			}
+		})
+	})
+	return completionID, nil
+}
+
+// DeleteCompletion removes a completion from the history.
+func (s *MemoryStore) DeleteCompletion(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.completions, func(c Completion) bool { return c.ID == id })
+	if i < 0 {
+		return fmt.Errorf("запись о выполнении не найдена")
+	}
+	s.completions = slices.Delete(s.completions, i, i+1)
+	return nil
+}
+
+// History returns the completions of a task, the latest first.
+func (s *MemoryStore) History(taskID string) ([]*Completion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.findCompletions(func(c Completion) bool { return c.TaskID == taskID }), nil
+}
+
+// Completions returns the completions done in [from, to), the latest first.
+func (s *MemoryStore) Completions(from, to time.Time) ([]*Completion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.findCompletions(func(c Completion) bool {
+		return (from.IsZero() || c.CompletedAt >= from.UTC().Format(TimestampFormat)) &&
+			(to.IsZero() || c.CompletedAt < to.UTC().Format(TimestampFormat))
+	}), nil
+}
+
+// findCompletions returns copies of the matching completions, the latest first.
+// The caller must hold the lock.
+func (s *MemoryStore) findCompletions(match func(Completion) bool) []*Completion {
+	found := []*Completion{}
+	for i := len(s.completions) - 1; i >= 0; i-- {
+		if c := s.completions[i]; match(c) {
+			found = append(found, &c)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].CompletedAt > found[j].CompletedAt })
+	return found
+}
+
+// Trash returns copies of the tasks in the trash, the most recently deleted first.
+func (s *MemoryStore) Trash() ([]*Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tasks := []*Task{}
+	for _, t := range s.tasks {
+		if t.DeletedAt != "" {
+			t.Tags = slices.Clone(t.Tags)
+			t.Checklist = s.checklist(t.ID)
+			tasks = append(tasks, &t)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].DeletedAt != tasks[j].DeletedAt {
+			return tasks[i].DeletedAt > tasks[j].DeletedAt
+		}
+		a, _ := strconv.ParseInt(tasks[i].ID, 10, 64)
+		b, _ := strconv.ParseInt(tasks[j].ID, 10, 64)
+		return a > b
+	})
+	return tasks, nil
+}
+
+// Restore moves a task from the trash back to the task list.
+func (s *MemoryStore) Restore(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.find(id)
+	if !ok || t.DeletedAt == "" {
+		return fmt.Errorf("задача не найдена в корзине")
+	}
+	s.track("restore", []string{t.ID}, func() {
+		t.DeletedAt = ""
+		t.Version++
+		key, _ := strconv.ParseInt(t.ID, 10, 64)
+		s.tasks[key] = t
+	})
+	return nil
+}
+
+// Purge deletes a task in the trash permanently.
+func (s *MemoryStore) Purge(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.find(id)
+	if !ok || t.DeletedAt == "" {
+		return fmt.Errorf("задача не найдена в корзине")
+	}
+	s.track("purge", []string{t.ID}, func() { s.removeTask(t.ID) })
+	return nil
+}
+
+// PurgeTrash permanently deletes the tasks moved to the trash before the given time
+// (all of them if before is zero) and returns their number.
+func (s *MemoryStore) PurgeTrash(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []string
+	for _, t := range s.tasks {
+		if t.DeletedAt != "" && (before.IsZero() || t.DeletedAt < before.UTC().Format(TimestampFormat)) {
+			ids = append(ids, t.ID)
+		}
+	}
+	sortIDs(ids)
+	s.track("purge", ids, func() {
+		for _, id := range ids {
+			s.removeTask(id)
+		}
+	})
+	return int64(len(ids)), nil
+}
+
+// trashTask moves a task to the trash, dropping its dependencies in both directions.
+// The caller must hold the lock.
+func (s *MemoryStore) trashTask(t Task, at time.Time) {
+	t.DeletedAt = at.UTC().Format(TimestampFormat)
+	t.Version++
+	key, _ := strconv.ParseInt(t.ID, 10, 64)
+	s.tasks[key] = t
+	delete(s.blockers, t.ID)
+	s.unblockDependents(t.ID)
+}
+
+// RevertAdd deletes permanently a task still at the version it was added with.
+func (s *MemoryStore) RevertAdd(id string, version int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.revertable(id, version)
+	if err != nil {
+		return err
+	}
+	s.track("undo", []string{t.ID}, func() {
+		s.track("unlink", s.dependents(t.ID), func() { s.removeTask(t.ID) })
+	})
+	return nil
+}
+
+// RevertDelete brings a deleted task back from the trash with its fields and links.
+func (s *MemoryStore) RevertDelete(old *Task, dependents []string, version int64) error {
+	return s.revert(old, dependents, "", version)
+}
+
+// RevertDone puts back a done task with its fields, checklist and links and removes its completion.
+func (s *MemoryStore) RevertDone(old *Task, dependents []string, completionID string, version int64) error {
+	return s.revert(old, dependents, completionID, version)
+}
+
+// revert writes back a task snapshot taken before an action, provided the task still
+// has the version the action left it at (see UndoStore); completionID, if set,
+// is removed from the history.
+func (s *MemoryStore) revert(old *Task, dependents []string, completionID string, version int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.revertable(old.ID, version)
+	if err != nil {
+		return err
+	}
+	s.track("undo", []string{t.ID}, func() {
+		s.track("link", dependents, func() {
+			restored := *old
+			restored.ID = t.ID
+			restored.Tags = s.useTags(old.Tags)
+			restored.Checklist = nil
+			restored.BlockedBy, restored.Blocked = nil, false
+			restored.DeletedAt = ""
+			restored.Version = t.Version + 1
+			key, _ := strconv.ParseInt(t.ID, 10, 64)
+			s.tasks[key] = restored
+
+			for _, item := range old.Checklist {
+				if taskID, i, ok := s.lookupItem(item.ID); ok && taskID == t.ID {
+					stored := &s.items[taskID][i]
+					stored.Title, stored.Done, stored.Required = item.Title, item.Done, item.Required
+				}
+			}
+			// link fails only with the errors relink skips.
+			relink(old, dependents, s.link)
+			s.completions = slices.DeleteFunc(s.completions, func(c Completion) bool {
+				return completionID != "" && c.ID == completionID
+			})
+		})
+	})
+	return nil
+}
+
+// revertable returns a task, in the trash or not, if it has the given version
+// (see UndoStore for the errors). The caller must hold the lock.
+func (s *MemoryStore) revertable(id string, version int64) (Task, error) {
+	t, ok := s.find(id)
+	if !ok {
+		return Task{}, fmt.Errorf("задача не найдена")
+	}
+	if t.Version != version {
+		return Task{}, fmt.Errorf("версия задачи устарела")
+	}
+	return t, nil
+}
+
+// As returns a view of the store sharing its data and recording writes under actor.
+func (s *MemoryStore) As(actor string) TaskStore {
+	return &MemoryStore{memoryData: s.memoryData, actor: actor}
+}
+
+// AuditLog returns copies of the audit log entries matching the filter, the latest first.
+func (s *MemoryStore) AuditLog(filter AuditFilter) ([]*AuditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := []*AuditEntry{}
+	for i := len(s.audit) - 1; i >= 0 && (filter.Limit <= 0 || len(entries) < filter.Limit); i-- {
+		if e := s.audit[i]; filter.match(&e) {
+			entries = append(entries, &e)
+		}
+	}
+	return entries, nil
+}
+
+// track runs change and records an audit entry for each of the tasks with the task state
+// before and after the change. The caller must hold the lock.
+func (s *MemoryStore) track(action string, ids []string, change func()) {
+	before := make([]*Task, len(ids))
+	for i, id := range ids {
+		before[i] = s.snapshot(id)
+	}
+	change()
+	for i, id := range ids {
+		s.record(id, action, before[i], s.snapshot(id))
+	}
+}
+
+// record appends an entry to the audit log unless the change left the task as it was.
+// The caller must hold the lock.
+func (s *MemoryStore) record(taskID, action string, before, after *Task) {
+	b, _ := auditState(before)
+	a, _ := auditState(after)
+	if bytes.Equal(b, a) {
+		return
+	}
+	s.audit = append(s.audit, AuditEntry{
+		ID:     strconv.Itoa(len(s.audit) + 1),
+		TaskID: taskID,
+		Action: action,
+		Actor:  auditActor(s.actor),
+		At:     time.Now().UTC().Format(TimestampFormat),
+		Before: b,
+		After:  a,
+	})
+}
+
+// snapshot returns a copy of a task with its checklist and blockers, including a task
+// in the trash; it is nil if the task does not exist. The caller must hold the lock.
+func (s *MemoryStore) snapshot(id string) *Task {
+	t, ok := s.find(id)
+	if !ok {
+		return nil
+	}
+	t.Tags = slices.Clone(t.Tags)
+	t.Checklist = s.checklist(t.ID)
+	s.fillBlockers(&t)
+	return &t
+}
@@ -0,0 +1,280 @@
+package db
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles holds numbered SQL migrations per dialect:
+// migrations/<driver>/NNNN_name.up.sql and NNNN_name.down.sql.
+//
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+// Migration is a single schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationState describes a migration and whether it is applied.
+// AppliedAt is empty for pending migrations.
+type MigrationState struct {
+	Version   int    `json:"version"`
+	Name      string `json:"name"`
+	AppliedAt string `json:"applied_at,omitempty"`
+}
+
+// migrator applies the migrations of its dialect to a database.
+type migrator struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+// Migrations returns embedded migrations of the current dialect ordered by version.
+func Migrations() ([]Migration, error) {
+	return dialectMigrations(current)
+}
+
+// dialectMigrations returns embedded migrations of a dialect ordered by version.
+func dialectMigrations(d dialect) ([]Migration, error) {
+	names, err := fs.Glob(migrationFiles, path.Join("migrations", string(d), "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, name := range names {
+		base := path.Base(name)
+		stem, direction, ok := strings.Cut(strings.TrimSuffix(base, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("некорректное имя файла миграции %q", base)
+		}
+		num, title, ok := strings.Cut(stem, "_")
+		version, err := strconv.Atoi(num)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("некорректный номер миграции %q", base)
+		}
+
+		body, err := migrationFiles.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: title}
+			byVersion[version] = m
+		} else if m.Name != title {
+			return nil, fmt.Errorf("разные имена у миграции %d: %q и %q", version, m.Name, title)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("у миграции %04d_%s нет up-файла", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// MigrateUp applies all pending migrations and returns how many were applied.
+// Each migration runs in its own transaction together with its schema_migrations record.
+func MigrateUp() (int, error) {
+	return migrator{DB, current}.up()
+}
+
+// up applies all pending migrations (see MigrateUp).
+func (m migrator) up() (int, error) {
+	migrations, applied, err := m.load()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, mg := range migrations {
+		if _, ok := applied[mg.Version]; ok {
+			continue
+		}
+		if err := m.apply(mg.Up,
+			"INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+			mg.Version, mg.Name, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			return count, fmt.Errorf("миграция %04d_%s: %w", mg.Version, mg.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// MigrateDown rolls back up to `steps` latest applied migrations and returns how many were rolled back.
+func MigrateDown(steps int) (int, error) {
+	return migrator{DB, current}.down(steps)
+}
+
+// down rolls back up to steps latest applied migrations (see MigrateDown).
+func (m migrator) down(steps int) (int, error) {
+	migrations, applied, err := m.load()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+		mg := migrations[i]
+		if _, ok := applied[mg.Version]; !ok {
+			continue
+		}
+		if mg.Down == "" {
+			return count, fmt.Errorf("миграция %04d_%s не поддерживает откат", mg.Version, mg.Name)
+		}
+		if err := m.apply(mg.Down,
+			"DELETE FROM schema_migrations WHERE version = ?", mg.Version); err != nil {
+			return count, fmt.Errorf("откат миграции %04d_%s: %w", mg.Version, mg.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// MigrationStatus lists all known migrations with their state.
+func MigrationStatus() ([]MigrationState, error) {
+	migrations, applied, err := migrator{DB, current}.load()
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		states = append(states, MigrationState{Version: m.Version, Name: m.Name, AppliedAt: applied[m.Version]})
+	}
+	return states, nil
+}
+
+// apply runs a migration script and updates schema_migrations in one transaction.
+func (m migrator) apply(script string, record string, args ...any) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(script); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(m.dialect.rebind(record), args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// load returns embedded migrations and applied versions with their timestamps.
+func (m migrator) load() ([]Migration, map[int]string, error) {
+	if err := m.prepare(); err != nil {
+		return nil, nil, err
+	}
+	migrations, err := dialectMigrations(m.dialect)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := m.db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]string{}
+	for rows.Next() {
+		var version int
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return migrations, applied, rows.Err()
+}
+
+// prepare creates the schema_migrations table.
+// A SQLite database that already has the scheduler table but no schema_migrations
+// was created before versioned migrations and is adopted at the baseline (see adoptLegacy).
+// Both steps run in one transaction, so a failed adoption leaves the database untouched.
+func (m migrator) prepare() error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	exists, err := m.tableExists(tx, "schema_migrations")
+	if err != nil || exists {
+		return err
+	}
+	legacy, err := m.tableExists(tx, "scheduler")
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+CREATE TABLE schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name VARCHAR(128) NOT NULL DEFAULT '',
+    applied_at VARCHAR(32) NOT NULL DEFAULT ''
+);`)
+	if err != nil {
+		return err
+	}
+	if legacy && m.dialect == DriverSQLite {
+		if err := m.adoptLegacy(tx); err != nil {
+			return fmt.Errorf("не удалось принять существующую базу данных: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// adoptLegacy records the baseline migration of a pre-migration database as applied.
+// Such databases were installed with the baseline schema, so its script is idempotent
+// and only creates what is missing; later migrations are then applied as usual.
+func (m migrator) adoptLegacy(tx *sql.Tx) error {
+	migrations, err := dialectMigrations(m.dialect)
+	if err != nil {
+		return err
+	}
+	if len(migrations) == 0 {
+		return nil
+	}
+	baseline := migrations[0]
+	if _, err := tx.Exec(baseline.Up); err != nil {
+		return err
+	}
+	_, err = tx.Exec(m.dialect.rebind("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"),
+		baseline.Version, baseline.Name, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+// tableExists reports whether the table exists in the database.
+func (m migrator) tableExists(tx *sql.Tx, table string) (bool, error) {
+	query := "SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+	if m.dialect == DriverPostgres {
+		query = "SELECT count(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?"
+	}
+	var count int
+	err := tx.QueryRow(m.dialect.rebind(query), table).Scan(&count)
+	return count > 0, err
+}
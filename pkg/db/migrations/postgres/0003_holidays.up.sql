@@ -0,0 +1,5 @@
+CREATE TABLE holidays (
+    id BIGSERIAL PRIMARY KEY,
+    date VARCHAR(8) NOT NULL UNIQUE,
+    title VARCHAR(256) NOT NULL DEFAULT ''
+);
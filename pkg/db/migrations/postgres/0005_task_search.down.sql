@@ -0,0 +1,3 @@
+-- Postgres searches task text with ILIKE (see pkg/db/search.go), so unlike SQLite
+-- it needs no full-text table; the migration keeps the versions of both dialects aligned.
+SELECT 1;
@@ -0,0 +1,3 @@
+DROP INDEX scheduler_project;
+ALTER TABLE scheduler DROP COLUMN project_id;
+DROP TABLE projects;
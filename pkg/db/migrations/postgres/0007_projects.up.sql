@@ -0,0 +1,9 @@
+CREATE TABLE projects (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(128) NOT NULL,
+    color VARCHAR(7) NOT NULL DEFAULT '',
+    archived BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+ALTER TABLE scheduler ADD COLUMN project_id BIGINT REFERENCES projects (id) ON DELETE SET NULL;
+CREATE INDEX scheduler_project ON scheduler (project_id);
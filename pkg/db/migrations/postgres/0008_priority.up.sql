@@ -0,0 +1 @@
+ALTER TABLE scheduler ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,9 @@
+CREATE TABLE checklist_items (
+    id BIGSERIAL PRIMARY KEY,
+    task_id BIGINT NOT NULL REFERENCES scheduler (id) ON DELETE CASCADE,
+    title VARCHAR(256) NOT NULL,
+    done BOOLEAN NOT NULL DEFAULT FALSE,
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    position INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX checklist_items_task ON checklist_items (task_id, position);
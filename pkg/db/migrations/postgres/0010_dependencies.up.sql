@@ -0,0 +1,7 @@
+-- task_id cannot start until blocker_id is done.
+CREATE TABLE task_dependencies (
+    task_id BIGINT NOT NULL REFERENCES scheduler (id) ON DELETE CASCADE,
+    blocker_id BIGINT NOT NULL REFERENCES scheduler (id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, blocker_id)
+);
+CREATE INDEX task_dependencies_blocker ON task_dependencies (blocker_id);
@@ -0,0 +1,4 @@
+-- NULL for active tasks; trashed tasks keep the time they were deleted (RFC 3339, UTC)
+-- until they are restored or purged.
+ALTER TABLE scheduler ADD COLUMN deleted_at VARCHAR(20);
+CREATE INDEX scheduler_deleted_at ON scheduler (deleted_at);
@@ -0,0 +1,22 @@
+-- Append-only log of task changes; before_state/after_state are JSON snapshots of the task
+-- (NULL before it was added and after it was purged). Entries outlive their tasks.
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    task_id BIGINT NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    actor VARCHAR(128) NOT NULL,
+    at VARCHAR(20) NOT NULL,
+    before_state TEXT,
+    after_state TEXT
+);
+CREATE INDEX audit_log_task ON audit_log (task_id);
+CREATE INDEX audit_log_at ON audit_log (at);
+
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
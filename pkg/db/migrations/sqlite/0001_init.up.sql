@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS scheduler (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    date CHAR(8) NOT NULL DEFAULT '',
+    title VARCHAR(256) NOT NULL DEFAULT '',
+    comment TEXT NOT NULL DEFAULT '',
+    repeat VARCHAR(128) NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_scheduler_date ON scheduler(date);
@@ -0,0 +1,3 @@
+ALTER TABLE scheduler DROP COLUMN repeat_count;
+ALTER TABLE scheduler DROP COLUMN repeat_until;
+ALTER TABLE scheduler DROP COLUMN roll;
@@ -0,0 +1,3 @@
+ALTER TABLE scheduler ADD COLUMN roll VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE scheduler ADD COLUMN repeat_until CHAR(8) NOT NULL DEFAULT '';
+ALTER TABLE scheduler ADD COLUMN repeat_count INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+CREATE TABLE holidays (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    date CHAR(8) NOT NULL UNIQUE,
+    title VARCHAR(256) NOT NULL DEFAULT ''
+);
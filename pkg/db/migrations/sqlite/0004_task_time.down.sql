@@ -0,0 +1,2 @@
+ALTER TABLE scheduler DROP COLUMN tz;
+ALTER TABLE scheduler DROP COLUMN time;
@@ -0,0 +1,4 @@
+DROP TRIGGER tags_delete;
+DROP TRIGGER scheduler_tags_delete;
+DROP TABLE task_tags;
+DROP TABLE tags;
@@ -0,0 +1,20 @@
+CREATE TABLE tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(64) NOT NULL UNIQUE
+);
+
+CREATE TABLE task_tags (
+    task_id INTEGER NOT NULL,
+    tag_id INTEGER NOT NULL,
+    PRIMARY KEY (task_id, tag_id)
+);
+CREATE INDEX task_tags_tag ON task_tags (tag_id);
+
+-- Foreign keys are not enforced by default in SQLite, so links are removed by triggers.
+CREATE TRIGGER scheduler_tags_delete AFTER DELETE ON scheduler BEGIN
+    DELETE FROM task_tags WHERE task_id = old.id;
+END;
+
+CREATE TRIGGER tags_delete AFTER DELETE ON tags BEGIN
+    DELETE FROM task_tags WHERE tag_id = old.id;
+END;
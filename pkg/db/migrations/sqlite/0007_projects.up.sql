@@ -0,0 +1,11 @@
+CREATE TABLE projects (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(128) NOT NULL,
+    color VARCHAR(7) NOT NULL DEFAULT '',
+    archived BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- NULL means the task is not in any project. Tasks of a deleted project are
+-- deleted or moved by the application, so no foreign key is declared here.
+ALTER TABLE scheduler ADD COLUMN project_id INTEGER;
+CREATE INDEX scheduler_project ON scheduler (project_id);
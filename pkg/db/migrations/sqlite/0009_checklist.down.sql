@@ -0,0 +1,2 @@
+DROP TRIGGER scheduler_checklist_delete;
+DROP TABLE checklist_items;
@@ -0,0 +1,13 @@
+CREATE TABLE checklist_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER NOT NULL,
+    title VARCHAR(256) NOT NULL,
+    done BOOLEAN NOT NULL DEFAULT FALSE,
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    position INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX checklist_items_task ON checklist_items (task_id, position);
+
+CREATE TRIGGER scheduler_checklist_delete AFTER DELETE ON scheduler BEGIN
+    DELETE FROM checklist_items WHERE task_id = old.id;
+END;
@@ -0,0 +1,2 @@
+DROP TRIGGER scheduler_dependencies_delete;
+DROP TABLE task_dependencies;
@@ -0,0 +1,11 @@
+-- task_id cannot start until blocker_id is done.
+CREATE TABLE task_dependencies (
+    task_id INTEGER NOT NULL,
+    blocker_id INTEGER NOT NULL,
+    PRIMARY KEY (task_id, blocker_id)
+);
+CREATE INDEX task_dependencies_blocker ON task_dependencies (blocker_id);
+
+CREATE TRIGGER scheduler_dependencies_delete AFTER DELETE ON scheduler BEGIN
+    DELETE FROM task_dependencies WHERE task_id = old.id OR blocker_id = old.id;
+END;
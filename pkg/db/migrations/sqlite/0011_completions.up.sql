@@ -0,0 +1,10 @@
+-- Completions outlive their tasks: title and date are snapshots taken when the task was done.
+CREATE TABLE completions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER NOT NULL,
+    title VARCHAR(256) NOT NULL DEFAULT '',
+    date CHAR(8) NOT NULL DEFAULT '',
+    completed_at VARCHAR(20) NOT NULL
+);
+CREATE INDEX completions_task ON completions (task_id, completed_at);
+CREATE INDEX completions_completed_at ON completions (completed_at);
@@ -0,0 +1,20 @@
+-- Append-only log of task changes; before_state/after_state are JSON snapshots of the task
+-- (NULL before it was added and after it was purged). Entries outlive their tasks.
+CREATE TABLE audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    actor VARCHAR(128) NOT NULL,
+    at VARCHAR(20) NOT NULL,
+    before_state TEXT,
+    after_state TEXT
+);
+CREATE INDEX audit_log_task ON audit_log (task_id);
+CREATE INDEX audit_log_at ON audit_log (at);
+
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
@@ -0,0 +1 @@
+ALTER TABLE scheduler DROP COLUMN version;
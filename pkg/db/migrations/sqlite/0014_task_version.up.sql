@@ -0,0 +1,2 @@
+-- Incremented on every change of a task row; PUT /api/task must name the version it edits.
+ALTER TABLE scheduler ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+-- The occurrence of the repeat rule a rolled task date was moved from ('' if the date is not rolled);
+-- the next occurrence is computed from it, so rolling does not shift the series.
+ALTER TABLE scheduler ADD COLUMN anchor VARCHAR(8) NOT NULL DEFAULT '';
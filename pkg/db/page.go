@@ -0,0 +1,189 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Sort fields of task lists.
+const (
+	// SortDate orders by date and time of day (default).
+	SortDate = "date"
+	// SortTitle orders by title.
+	SortTitle = "title"
+	// SortID orders by creation (ID).
+	SortID = "id"
+	// SortRank orders full-text search results by relevance, best first.
+	// Without a full-text search it orders by ID.
+	SortRank = "rank"
+	// SortSmart puts overdue tasks first, the most important and oldest of them on top,
+	// followed by upcoming tasks by date, then priority (highest first), then time of day.
+	SortSmart = "smart"
+)
+
+// MaxPriority is the highest task priority; priorities range from 1 to MaxPriority, 0 means none.
+const MaxPriority = 4
+
+// sortKeys maps sort fields to SQL expressions of the keyset key.
+// Ties are always broken by id, so (key, id) is unique.
+// The rank key depends on the query and is supplied by taskQuery,
+// the smart key depends on the date and is built by smartKey.
+var sortKeys = map[string]string{
+	SortDate:  "date || time",
+	SortTitle: "title",
+	SortID:    "''",
+	SortRank:  "",
+	SortSmart: "",
+}
+
+// Cursor is a keyset position: the sort key and ID of the last task of the previous page.
+type Cursor struct {
+	Key string
+	ID  int64
+}
+
+// Page selects a window of a task list.
+//
+// Sort is one of SortDate, SortTitle, SortID, SortRank or SortSmart (SortDate if empty);
+// Desc reverses the order. After, if set, skips tasks up to and including that position.
+// Today (YYYYMMDD) is the reference date of SortSmart and must be set for it.
+type Page struct {
+	Limit int
+	Sort  string
+	Desc  bool
+	After *Cursor
+	Today string
+}
+
+// CheckSort validates a sort field.
+func CheckSort(sort string) error {
+	if _, ok := sortKeys[sort]; !ok && sort != "" {
+		return fmt.Errorf("некорректное поле сортировки %q (допустимо date, title, id, rank или smart)", sort)
+	}
+	return nil
+}
+
+// sortField returns the effective sort field.
+func (p Page) sortField() string {
+	if p.Sort == "" {
+		return SortDate
+	}
+	return p.Sort
+}
+
+// Next returns the cursor pointing after the task (the last one of a page).
+func (p Page) Next(task *Task) (*Cursor, error) {
+	id, err := strconv.ParseInt(task.ID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный идентификатор задачи %q", task.ID)
+	}
+	return &Cursor{Key: p.key(task), ID: id}, nil
+}
+
+// key returns the sort key of the task; it matches the SQL expression in sortKeys.
+func (p Page) key(task *Task) string {
+	switch p.sortField() {
+	case SortTitle:
+		return task.Title
+	case SortID:
+		return ""
+	case SortRank:
+		return strconv.FormatFloat(task.Rank, 'g', -1, 64)
+	case SortSmart:
+		return SmartKey(task, p.Today)
+	}
+	return task.Date + task.Time
+}
+
+// SmartKey returns the SortSmart key of a task for the given day (YYYYMMDD):
+// tasks ordered by the key, then by ID, go in the smart order.
+//
+// Overdue tasks get "0", the inverted priority, date and time; other tasks get
+// "1", date, the inverted priority and time. Tasks without a priority go last.
+func SmartKey(task *Task, today string) string {
+	rank := strconv.Itoa(MaxPriority - task.Priority)
+	if task.Date < today {
+		return "0" + rank + task.Date + task.Time
+	}
+	return "1" + task.Date + rank + task.Time
+}
+
+// SmartOrder sorts tasks in place in the SortSmart order for the given day,
+// so that agenda-like views list tasks the same way as GET /api/tasks?sort=smart.
+func SmartOrder(tasks []*Task, today string) {
+	page := Page{Sort: SortSmart, Today: today}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		ki, ii := page.position(tasks[i])
+		kj, ij := page.position(tasks[j])
+		return page.less(ki, ii, kj, ij)
+	})
+}
+
+// smartKey returns the SQL expression of SmartKey for the day.
+// The day is inlined as a literal, so it must consist of digits only.
+func smartKey(today string) string {
+	for _, r := range today {
+		if r < '0' || r > '9' {
+			today = ""
+			break
+		}
+	}
+	rank := fmt.Sprintf("CAST(%d - priority AS VARCHAR)", MaxPriority)
+	return fmt.Sprintf("(CASE WHEN date < '%s' THEN '0' || %s || date || time ELSE '1' || date || %s || time END)",
+		today, rank, rank)
+}
+
+// clause returns the keyset condition (possibly empty) and the ORDER BY ... LIMIT tail for SQL queries.
+// rank is the SQL expression of the relevance of a row.
+func (p Page) clause(rank string) (where string, args []any, tail string) {
+	key := sortKeys[p.sortField()]
+	var after any
+	if p.After != nil {
+		after = p.After.Key
+	}
+	switch p.sortField() {
+	case SortRank:
+		key = rank
+		if p.After != nil {
+			after, _ = strconv.ParseFloat(p.After.Key, 64)
+		}
+	case SortSmart:
+		key = smartKey(p.Today)
+	}
+
+	op, dir := ">", "ASC"
+	if p.Desc {
+		op, dir = "<", "DESC"
+	}
+
+	if p.After != nil {
+		where = fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", key, op)
+		args = []any{after, after, p.After.ID}
+	}
+	tail = fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %d", key, dir, dir, p.Limit)
+	return where, args, tail
+}
+
+// less reports whether the position (ka, ia) goes before (kb, ib) in the page order.
+func (p Page) less(ka string, ia int64, kb string, ib int64) bool {
+	if p.Desc {
+		ka, ia, kb, ib = kb, ib, ka, ia
+	}
+	return ka < kb || (ka == kb && ia < ib)
+}
+
+// position returns the sort key and numeric ID of the task.
+func (p Page) position(task *Task) (string, int64) {
+	id, _ := strconv.ParseInt(task.ID, 10, 64)
+	return p.key(task), id
+}
+
+// after reports whether the task lies after the page cursor.
+func (p Page) after(task *Task) bool {
+	if p.After == nil {
+		return true
+	}
+	k, id := p.position(task)
+	return p.less(p.After.Key, p.After.ID, k, id)
+}
@@ -0,0 +1,6 @@
+//go:build postgres
+
+package db
+
+// The Postgres driver is optional: it is linked only in builds with "-tags postgres".
+import _ "github.com/jackc/pgx/v5/stdlib"
@@ -0,0 +1,172 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// NoProject is the Filter.Project value selecting tasks without a project.
+const NoProject = "none"
+
+// Project groups tasks into a list.
+//
+// Color is an optional "#rrggbb" value for the UI. Tasks of archived projects
+// are hidden from the task list unless the project is asked for explicitly.
+// Tasks is the number of tasks in the project (set by Projects and GetProject).
+type Project struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Color    string `json:"color"`
+	Archived bool   `json:"archived"`
+	Tasks    int    `json:"tasks"`
+}
+
+// projectQuery selects projects with their task counts; conditions go before groupProjects.
+const projectQuery = `SELECT projects.id, projects.name, projects.color, projects.archived, COUNT(scheduler.id)
+	FROM projects LEFT JOIN scheduler ON scheduler.project_id = projects.id AND scheduler.deleted_at IS NULL`
+
+// groupProjects completes projectQuery.
+const groupProjects = ` GROUP BY projects.id, projects.name, projects.color, projects.archived`
+
+// nullID converts an optional ID to a query argument: NULL if it is empty.
+func nullID(id string) any {
+	if id == "" {
+		return nil
+	}
+	return id
+}
+
+// Projects returns projects ordered by name; archived ones are included only if archived is set.
+func (s *SQLStore) Projects(archived bool) ([]*Project, error) {
+	query := projectQuery
+	if !archived {
+		query += " WHERE projects.archived = FALSE"
+	}
+	rows, err := s.db.Query(query + groupProjects + " ORDER BY projects.name, projects.id")
+	if err != nil {
+		return []*Project{}, err
+	}
+	defer rows.Close()
+
+	projects := []*Project{}
+	for rows.Next() {
+		p := &Project{}
+		if err := rows.Scan(&p.ID, &p.Name, &p.Color, &p.Archived, &p.Tasks); err != nil {
+			return []*Project{}, err
+		}
+		projects = append(projects, p)
+	}
+	if err := rows.Err(); err != nil {
+		return []*Project{}, err
+	}
+	return projects, nil
+}
+
+// GetProject returns a single project by id.
+func (s *SQLStore) GetProject(id string) (*Project, error) {
+	p := &Project{}
+	err := s.db.QueryRow(s.dialect.rebind(projectQuery+" WHERE projects.id = ?"+groupProjects), id).
+		Scan(&p.ID, &p.Name, &p.Color, &p.Archived, &p.Tasks)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("проект не найден")
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// AddProject inserts a new project and returns its auto-generated database ID.
+func (s *SQLStore) AddProject(p *Project) (int64, error) {
+	return s.dialect.insert(s.db, "INSERT INTO projects (name, color, archived) VALUES (?, ?, ?)",
+		p.Name, p.Color, p.Archived)
+}
+
+// UpdateProject updates name, color and the archived flag of a project.
+func (s *SQLStore) UpdateProject(p *Project) error {
+	res, err := s.db.Exec(s.dialect.rebind("UPDATE projects SET name = ?, color = ?, archived = ? WHERE id = ?"),
+		p.Name, p.Color, p.Archived, p.ID)
+	if err != nil {
+		return err
+	}
+	return projectAffected(res)
+}
+
+// DeleteProject deletes a project in a single transaction. Its tasks are moved to the trash
+// (without a project) if cascade is set, otherwise they are moved to the project moveTo ("" for no project).
+func (s *SQLStore) DeleteProject(id string, cascade bool, moveTo string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ids, err := s.taskIDs(tx, "SELECT id FROM scheduler WHERE project_id = ? ORDER BY id", id)
+	if err != nil {
+		return err
+	}
+	action := "move"
+	if cascade {
+		action = "delete"
+	}
+	err = s.track(tx, action, ids, func() error {
+		if !cascade {
+			_, err := tx.Exec(s.dialect.rebind("UPDATE scheduler SET project_id = ?, version = version + 1 WHERE project_id = ?"), nullID(moveTo), id)
+			return err
+		}
+		return s.trackDependents(tx, ids, func() error {
+			return s.trashProject(tx, id)
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(s.dialect.rebind("DELETE FROM projects WHERE id = ?"), id)
+	if err != nil {
+		return err
+	}
+	if err := projectAffected(res); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MoveTask moves a task to a project ("" for no project) and returns its new version.
+// A non-zero version must match the task version.
+func (s *SQLStore) MoveTask(id, projectID string, version int64) (int64, error) {
+	var moved int64
+	err := s.transaction(func(tx *sql.Tx) error {
+		return s.track(tx, "move", []string{id}, func() error {
+			res, err := tx.Exec(s.dialect.rebind(
+				"UPDATE scheduler SET project_id = ?, version = version + 1 WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)"),
+				nullID(projectID), id, version, version)
+			if err != nil {
+				return err
+			}
+
+			rowsAffected, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if rowsAffected == 0 {
+				return s.missingOrStale(tx, id)
+			}
+			return tx.QueryRow(s.dialect.rebind("SELECT version FROM scheduler WHERE id = ?"), id).Scan(&moved)
+		})
+	})
+	return moved, err
+}
+
+// projectAffected reports a missing project if the statement changed no rows.
+func projectAffected(res sql.Result) error {
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("проект не найден")
+	}
+	return nil
+}
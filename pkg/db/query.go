@@ -0,0 +1,173 @@
+package db
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Filter narrows a task list. Zero values (and nil) disable the corresponding condition;
+// all set conditions must hold.
+//
+// Dates use DateFormat (YYYYMMDD). Today is the reference date for Overdue
+// and DueWithin and must be set when any of them is used.
+type Filter struct {
+	// Search is a query in the search language (see ParseSearch).
+	Search string
+	// From and To limit the task date range (inclusive).
+	From string
+	To   string
+	// Repeating selects repeating (true) or one-off (false) tasks.
+	Repeating *bool
+	// Overdue selects tasks dated before Today.
+	Overdue bool
+	// DueWithin selects tasks dated from Today to Today + DueWithin days.
+	DueWithin *int
+	Today     string
+	// Tags selects tasks having every listed tag.
+	Tags []string
+	// Project selects tasks of one project, or tasks without a project if it is NoProject.
+	Project string
+	// Archived includes tasks of archived projects; they are hidden unless Project is set.
+	Archived bool
+}
+
+// hidesArchived reports whether tasks of archived projects are excluded.
+func (f Filter) hidesArchived() bool {
+	return f.Project == "" && !f.Archived
+}
+
+// dueLimit returns the last date matched by DueWithin.
+func (f Filter) dueLimit() string {
+	today, err := time.Parse(DateFormat, f.Today)
+	if err != nil {
+		return f.Today
+	}
+	return today.AddDate(0, 0, *f.DueWithin).Format(DateFormat)
+}
+
+// search parses the search query; it is nil if Search is empty.
+func (f Filter) search() (*SearchQuery, error) {
+	if strings.TrimSpace(f.Search) == "" {
+		return nil, nil
+	}
+	return ParseSearch(f.Search)
+}
+
+// apply adds the filter conditions to the query; tasks in the trash never match.
+// A malformed search query is reported as *QueryError.
+func (f Filter) apply(q *taskQuery) error {
+	q.where("deleted_at IS NULL")
+	search, err := f.search()
+	if err != nil {
+		return err
+	}
+	if search != nil {
+		if err := search.apply(q); err != nil {
+			return err
+		}
+	}
+	if f.From != "" {
+		q.where("date >= ?", f.From)
+	}
+	if f.To != "" {
+		q.where("date <= ?", f.To)
+	}
+	if f.Repeating != nil {
+		if *f.Repeating {
+			q.where("repeat <> ''")
+		} else {
+			q.where("repeat = ''")
+		}
+	}
+	if f.Overdue {
+		q.where("date < ?", f.Today)
+	}
+	if f.DueWithin != nil {
+		q.where("date >= ? AND date <= ?", f.Today, f.dueLimit())
+	}
+	switch {
+	case f.Project == NoProject:
+		q.where("project_id IS NULL")
+	case f.Project != "":
+		q.where("project_id = ?", f.Project)
+	case f.hidesArchived():
+		q.where("(project_id IS NULL OR project_id NOT IN (SELECT id FROM projects WHERE archived = TRUE))")
+	}
+	for _, tag := range f.Tags {
+		q.where(`id IN (SELECT task_tags.task_id FROM task_tags
+			JOIN tags ON tags.id = task_tags.tag_id WHERE tags.name = ?)`, tag)
+	}
+	return nil
+}
+
+// match reports whether the task satisfies the filter with the parsed search query (may be nil);
+// it mirrors apply for in-memory stores, except for hiding archived projects.
+func (f Filter) match(t *Task, search *SearchQuery) bool {
+	if search != nil && !search.match(t) {
+		return false
+	}
+	switch {
+	case f.From != "" && t.Date < f.From,
+		f.To != "" && t.Date > f.To,
+		f.Repeating != nil && *f.Repeating != (t.Repeat != ""),
+		f.Overdue && t.Date >= f.Today,
+		f.DueWithin != nil && (t.Date < f.Today || t.Date > f.dueLimit()),
+		f.Project == NoProject && t.ProjectID != "",
+		f.Project != "" && f.Project != NoProject && t.ProjectID != f.Project:
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !slices.Contains(t.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// taskQuery builds a parameterized SELECT over the scheduler table.
+// Conditions are combined with AND; placeholders are written as "?".
+//
+// If match is set, the query is joined with the scheduler_fts full-text index
+// and selects searchColumns as well.
+type taskQuery struct {
+	dialect dialect
+	match   string
+	conds   []string
+	args    []any
+}
+
+// ftsJoin selects matching rows of the full-text index with their relevance and snippet.
+const ftsJoin = ` JOIN (
+	SELECT rowid, rank, snippet(scheduler_fts, -1, '[', ']', '…', 12) AS snippet
+	FROM scheduler_fts WHERE scheduler_fts MATCH ?
+) AS fts ON fts.rowid = scheduler.id`
+
+// where adds a condition with its arguments.
+func (q *taskQuery) where(cond string, args ...any) {
+	q.conds = append(q.conds, cond)
+	q.args = append(q.args, args...)
+}
+
+// build returns the final query for the page and its arguments.
+func (q *taskQuery) build(page Page) (string, []any) {
+	rank := "0"
+	if q.match != "" {
+		rank = "fts.rank"
+	}
+	keyset, keyArgs, tail := page.clause(rank)
+	if keyset != "" {
+		q.where(keyset, keyArgs...)
+	}
+
+	query := "SELECT " + taskColumns + " FROM scheduler"
+	var args []any
+	if q.match != "" {
+		query = "SELECT " + taskColumns + ", " + searchColumns + " FROM scheduler" + ftsJoin
+		args = append(args, q.match)
+	}
+	if len(q.conds) > 0 {
+		query += " WHERE " + strings.Join(q.conds, " AND ")
+	}
+	return q.dialect.rebind(query + tail), append(args, q.args...)
+}
@@ -0,0 +1,340 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// QueryError reports a malformed search query; handlers answer it with 400.
+type QueryError struct {
+	Msg string
+}
+
+func (e *QueryError) Error() string {
+	return "некорректный поисковый запрос: " + e.Msg
+}
+
+// Search query fields (qualifiers written as "field:value").
+const (
+	fieldText    = "" // title or comment
+	fieldTitle   = "title"
+	fieldComment = "comment"
+	fieldRepeat  = "repeat"
+	fieldBefore  = "before"
+	fieldAfter   = "after"
+	fieldDate    = "date"
+)
+
+var searchFields = map[string]bool{
+	fieldTitle: true, fieldComment: true, fieldRepeat: true,
+	fieldBefore: true, fieldAfter: true, fieldDate: true,
+}
+
+// searchOperators are boolean operators between text terms.
+var searchOperators = map[string]bool{"AND": true, "OR": true, "NOT": true}
+
+// searchTerm is a single element of a search query: either an operator or a condition.
+type searchTerm struct {
+	op     string // "AND", "OR" or "NOT"; the other fields are empty then
+	field  string
+	value  string
+	prefix bool // trailing "*" of a text term
+	negate bool // leading "-"
+}
+
+// SearchQuery is a parsed search string (see ParseSearch).
+type SearchQuery struct {
+	terms []searchTerm
+}
+
+// ParseSearch parses the search query language:
+//
+//	отчёт отч* "квартальный отчёт"   words, prefixes and phrases in title or comment
+//	план OR отчёт, отчёт NOT черновик boolean operators between text terms (AND is implied)
+//	title:отчёт comment:"для бухгалтерии"
+//	repeat:w                          repeat rule starting with the value
+//	before:20261201 after:20260101    dates (YYYYMMDD or DD.MM.YYYY), exclusive
+//	date:20261201, 01.12.2026         exact date
+//	-comment:draft -черновик          negation of any condition
+//
+// Malformed queries are reported as *QueryError.
+func ParseSearch(search string) (*SearchQuery, error) {
+	q := &SearchQuery{}
+	rest := strings.TrimSpace(search)
+	for rest != "" {
+		var t searchTerm
+		var err error
+		t, rest, err = parseSearchTerm(rest)
+		if err != nil {
+			return nil, err
+		}
+		if t.op != "" || t.field != fieldText || t.value != "" {
+			q.terms = append(q.terms, t)
+		}
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+	}
+	if len(q.terms) == 0 {
+		return nil, &QueryError{Msg: "нет условий для поиска"}
+	}
+	return q, q.checkOperators()
+}
+
+// parseSearchTerm reads one term from the start of s and returns the rest.
+func parseSearchTerm(s string) (searchTerm, string, error) {
+	var t searchTerm
+
+	end := strings.IndexFunc(s, unicode.IsSpace)
+	if end < 0 {
+		end = len(s)
+	}
+	if searchOperators[s[:end]] {
+		t.op = s[:end]
+		return t, s[end:], nil
+	}
+
+	if len(s) > 1 && s[0] == '-' {
+		t.negate = true
+		s = s[1:]
+	}
+	if name, value, ok := strings.Cut(s, ":"); ok && isFieldName(name) {
+		if !searchFields[name] {
+			return t, "", &QueryError{Msg: fmt.Sprintf("неизвестное поле %q (допустимо title, comment, repeat, before, after, date)", name)}
+		}
+		t.field = name
+		s = value
+	}
+
+	if strings.HasPrefix(s, `"`) {
+		end := strings.IndexByte(s[1:], '"')
+		if end < 0 {
+			return t, "", &QueryError{Msg: "незакрытая кавычка"}
+		}
+		t.value = s[1 : end+1]
+		s = s[end+2:]
+		if strings.HasPrefix(s, "*") {
+			t.prefix = true
+			s = s[1:]
+		}
+	} else {
+		end := strings.IndexFunc(s, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+		if end < 0 {
+			end = len(s)
+		}
+		t.value = strings.TrimRight(s[:end], "*")
+		t.prefix = t.value != s[:end]
+		s = s[end:]
+	}
+
+	if strings.TrimSpace(t.value) == "" {
+		if t.field != fieldText || t.negate {
+			return t, "", &QueryError{Msg: fmt.Sprintf("пустое значение условия %s", t)}
+		}
+		return t, s, nil
+	}
+	return t, s, t.normalize()
+}
+
+// isFieldName reports whether s looks like a qualifier (lowercase latin letters),
+// so that text such as "18:00" is searched as is.
+func isFieldName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// normalize validates the term value; dates are converted to YYYYMMDD.
+// A bare DD.MM.YYYY text term becomes a date term.
+func (t *searchTerm) normalize() error {
+	if t.field == fieldText {
+		if date, err := time.Parse("02.01.2006", t.value); err == nil && !t.prefix {
+			t.field, t.value = fieldDate, date.Format(DateFormat)
+		}
+		return nil
+	}
+
+	switch t.field {
+	case fieldBefore, fieldAfter, fieldDate:
+		date, err := time.Parse(DateFormat, t.value)
+		if err != nil {
+			date, err = time.Parse("02.01.2006", t.value)
+		}
+		if err != nil {
+			return &QueryError{Msg: fmt.Sprintf("некорректная дата в условии %s (ожидается ГГГГММДД или ДД.ММ.ГГГГ)", t)}
+		}
+		t.value = date.Format(DateFormat)
+	}
+	return nil
+}
+
+// text reports whether the term searches title/comment text without negation,
+// i.e. can take part in boolean operators and full-text ranking.
+func (t searchTerm) text() bool {
+	return t.op == "" && !t.negate && (t.field == fieldText || t.field == fieldTitle || t.field == fieldComment)
+}
+
+// String returns the term as written in a query.
+func (t searchTerm) String() string {
+	if t.op != "" {
+		return t.op
+	}
+	s := t.value
+	if t.field != fieldText {
+		s = t.field + ":" + s
+	}
+	if t.negate {
+		s = "-" + s
+	}
+	return s
+}
+
+// checkOperators verifies that every operator stands between two text terms.
+func (q *SearchQuery) checkOperators() error {
+	for i, t := range q.terms {
+		if t.op == "" {
+			continue
+		}
+		if i == 0 || !q.terms[i-1].text() {
+			return &QueryError{Msg: fmt.Sprintf("оператор %s без левого операнда-текста", t.op)}
+		}
+		if i == len(q.terms)-1 || !q.terms[i+1].text() {
+			return &QueryError{Msg: fmt.Sprintf("оператор %s без правого операнда-текста", t.op)}
+		}
+	}
+	return nil
+}
+
+// FullText reports whether the query has text terms, so results can be ranked by relevance.
+func (q *SearchQuery) FullText() bool {
+	for _, t := range q.terms {
+		if t.text() {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPortable rejects OR and NOT, which are supported only by the SQLite full-text index.
+func (q *SearchQuery) checkPortable() error {
+	for _, t := range q.terms {
+		if t.op == "OR" || t.op == "NOT" {
+			return &QueryError{Msg: fmt.Sprintf("оператор %s поддерживается только в хранилище SQLite", t.op)}
+		}
+	}
+	return nil
+}
+
+// apply adds the query conditions to a SQL query.
+//
+// For SQLite, text terms with operators form one FTS5 MATCH expression (ranked),
+// negated text terms exclude matches of their own expression.
+// Other dialects search text with case-insensitive LIKE.
+func (q *SearchQuery) apply(tq *taskQuery) error {
+	fts := tq.dialect == DriverSQLite
+	if !fts {
+		if err := q.checkPortable(); err != nil {
+			return err
+		}
+	}
+
+	var match []string
+	for _, t := range q.terms {
+		switch {
+		case t.op != "":
+			if fts {
+				match = append(match, t.op)
+			}
+		case fts && t.text():
+			if len(match) > 0 && !searchOperators[match[len(match)-1]] {
+				match = append(match, "AND")
+			}
+			match = append(match, t.fts())
+		case fts && t.negate && (t.field == fieldText || t.field == fieldTitle || t.field == fieldComment):
+			tq.where("id NOT IN (SELECT rowid FROM scheduler_fts WHERE scheduler_fts MATCH ?)", t.fts())
+		default:
+			cond, args := t.sql(tq.dialect)
+			if t.negate {
+				cond = "NOT " + cond
+			}
+			tq.where(cond, args...)
+		}
+	}
+	tq.match = strings.Join(match, " ")
+	return nil
+}
+
+// fts returns the FTS5 expression of a text term. The value is quoted,
+// so FTS5 syntax characters in it are plain text.
+func (t searchTerm) fts() string {
+	s := `"` + strings.ReplaceAll(t.value, `"`, `""`) + `"`
+	if t.prefix {
+		s += "*"
+	}
+	if t.field != fieldText {
+		s = t.field + " : " + s
+	}
+	return s
+}
+
+// likeEscaper escapes LIKE wildcards, so that they match themselves with ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// sql returns a parenthesized condition of the term (without negation).
+// Text is matched as a substring: "%" and "_" in the value are not wildcards.
+func (t searchTerm) sql(d dialect) (string, []any) {
+	pattern := "%" + likeEscaper.Replace(t.value) + "%"
+	switch t.field {
+	case fieldTitle, fieldComment:
+		return fmt.Sprintf(`(%s %s ? ESCAPE '\')`, t.field, d.like()), []any{pattern}
+	case fieldRepeat:
+		return "(substr(repeat, 1, ?) = ?)", []any{utf8.RuneCountInString(t.value), t.value}
+	case fieldBefore:
+		return "(date < ?)", []any{t.value}
+	case fieldAfter:
+		return "(date > ?)", []any{t.value}
+	case fieldDate:
+		return "(date = ?)", []any{t.value}
+	}
+	return fmt.Sprintf(`(title %[1]s ? ESCAPE '\' OR comment %[1]s ? ESCAPE '\')`, d.like()), []any{pattern, pattern}
+}
+
+// match reports whether the task satisfies all terms; it mirrors apply with LIKE for in-memory stores.
+// The query must pass checkPortable.
+func (q *SearchQuery) match(task *Task) bool {
+	for _, t := range q.terms {
+		if t.op == "" && t.match(task) == t.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// match reports whether the task satisfies the term (without negation).
+func (t searchTerm) match(task *Task) bool {
+	contains := func(s string) bool {
+		return strings.Contains(strings.ToLower(s), strings.ToLower(t.value))
+	}
+	switch t.field {
+	case fieldTitle:
+		return contains(task.Title)
+	case fieldComment:
+		return contains(task.Comment)
+	case fieldRepeat:
+		return strings.HasPrefix(task.Repeat, t.value)
+	case fieldBefore:
+		return task.Date < t.value
+	case fieldAfter:
+		return task.Date > t.value
+	case fieldDate:
+		return task.Date == t.value
+	}
+	return contains(task.Title) || contains(task.Comment)
+}
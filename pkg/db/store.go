@@ -0,0 +1,234 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// TaskStore is the task storage used by the API handlers.
+//
+// Implementations return an error with the text "задача не найдена"
+// (or "task not found" from Get) when the task does not exist.
+// Tasks in the trash are treated as missing by all methods except TrashStore ones.
+type TaskStore interface {
+	TagStore
+	ProjectStore
+	ChecklistStore
+	DependencyStore
+	CompletionStore
+	TrashStore
+	AuditStore
+	UndoStore
+	HolidayStore
+
+	// Add inserts a new task with its tags and checklist and returns its ID.
+	Add(task *Task) (int64, error)
+	// Get returns a single task by id.
+	Get(id string) (*Task, error)
+	// Update replaces all fields of an existing task.
+	// The task tags are replaced too, unless task.Tags is nil; the checklist is kept.
+	// If task.Version is set and the task has another version, an error with the text
+	// "версия задачи устарела" is returned; on success task.Version is set to the new version.
+	Update(task *Task) error
+	// Delete moves a task to the trash; its dependencies are dropped.
+	Delete(id string) error
+	// List returns a page of tasks matching the filter (see Filter and Page).
+	List(filter Filter, page Page) ([]*Task, error)
+	// UpdateDate moves a task to the next date (an occurrence that is not rolled), consumes
+	// one occurrence of repeat_count, resets the task checklist and unblocks the tasks depending on it.
+	UpdateDate(next string, id string) error
+}
+
+// TagStore manages the tag dictionary shared by all tasks.
+//
+// Tags are created implicitly when a task is saved with a new tag name.
+// Implementations return an error with the text "тег не найден" when the tag
+// does not exist and "тег уже существует" when the name is taken.
+type TagStore interface {
+	// Tags returns all tags ordered by name, with the number of tasks for each.
+	Tags() ([]*Tag, error)
+	// AddTag creates a tag and returns its ID.
+	AddTag(name string) (int64, error)
+	// RenameTag changes the name of a tag; its tasks keep the tag.
+	RenameTag(tag *Tag) error
+	// DeleteTag removes a tag from all tasks and deletes it.
+	DeleteTag(id string) error
+}
+
+// ProjectStore manages projects (task lists).
+//
+// Implementations return an error with the text "проект не найден"
+// when the project does not exist.
+type ProjectStore interface {
+	// Projects returns projects ordered by name with the number of tasks in each;
+	// archived projects are included only if archived is set.
+	Projects(archived bool) ([]*Project, error)
+	// GetProject returns a single project by id.
+	GetProject(id string) (*Project, error)
+	// AddProject inserts a new project and returns its ID.
+	AddProject(project *Project) (int64, error)
+	// UpdateProject replaces name, color and the archived flag of a project.
+	UpdateProject(project *Project) error
+	// DeleteProject deletes a project moving its tasks to the trash (cascade)
+	// or to the project moveTo ("" for no project).
+	DeleteProject(id string, cascade bool, moveTo string) error
+	// MoveTask moves a task to a project ("" for no project) and returns its new version;
+	// a non-zero version must match the task version ("версия задачи устарела" otherwise).
+	MoveTask(id, projectID string, version int64) (int64, error)
+}
+
+// ChecklistStore manages checklist items of tasks.
+//
+// Implementations return an error with the text "пункт не найден"
+// when the item does not exist.
+type ChecklistStore interface {
+	// Checklist returns the items of a task in their order.
+	Checklist(taskID string) ([]*ChecklistItem, error)
+	// GetItem returns a single item by id.
+	GetItem(id string) (*ChecklistItem, error)
+	// AddItem appends an item to the checklist of item.TaskID and returns its ID.
+	AddItem(item *ChecklistItem) (int64, error)
+	// UpdateItem replaces title, done and required of an item.
+	UpdateItem(item *ChecklistItem) error
+	// DeleteItem removes an item by id.
+	DeleteItem(id string) error
+	// ReorderChecklist sets the order of the task items;
+	// ids must list every item of the task exactly once.
+	ReorderChecklist(taskID string, ids []string) error
+}
+
+// DependencyStore manages "task cannot start until blocker is done" links.
+//
+// A link lasts until the blocker is done: it is removed when the blocker
+// is deleted or moves to its next occurrence.
+type DependencyStore interface {
+	// Link makes taskID depend on blockerID. It returns an error with the text
+	// "цикл зависимостей" if the link would close a cycle.
+	Link(taskID, blockerID string) error
+	// Unlink removes a link; "зависимость не найдена" is returned if there is none.
+	Unlink(taskID, blockerID string) error
+	// Dependents returns IDs of the tasks blocked by the task.
+	Dependents(taskID string) ([]string, error)
+}
+
+// CompletionStore keeps the history of done tasks.
+//
+// Implementations return an error with the text "запись о выполнении не найдена"
+// when the completion does not exist.
+type CompletionStore interface {
+	// Complete records a completion of the task at the given time and, atomically with it,
+	// moves the task to the trash (next is empty) or to the next date like UpdateDate;
+	// anchor is the occurrence next was rolled from (see Task.Anchor).
+	// If version is set and the task has another version, nothing is changed and an error
+	// with the text "версия задачи устарела" is returned. It returns the ID of the completion.
+	Complete(id string, version int64, next, anchor string, at time.Time) (int64, error)
+	// DeleteCompletion removes a completion from the history, e.g. when "done" is undone.
+	DeleteCompletion(id string) error
+	// History returns the completions of a task, the latest first.
+	// The history is kept after the task is deleted.
+	History(taskID string) ([]*Completion, error)
+	// Completions returns the completions done in [from, to), the latest first;
+	// a zero from/to means the range is not limited on that side.
+	Completions(from, to time.Time) ([]*Completion, error)
+}
+
+// TrashStore manages deleted tasks kept until they are restored or purged.
+//
+// Implementations return an error with the text "задача не найдена в корзине"
+// when the task is not in the trash.
+type TrashStore interface {
+	// Trash returns the tasks in the trash, the most recently deleted first.
+	Trash() ([]*Task, error)
+	// Restore moves a task from the trash back to the task list.
+	Restore(id string) error
+	// Purge deletes a task in the trash permanently.
+	Purge(id string) error
+	// PurgeTrash permanently deletes the tasks moved to the trash before the given time
+	// (all of them if before is zero) and returns their number.
+	PurgeTrash(before time.Time) (int64, error)
+}
+
+// HolidayStore manages the holiday calendar used by business-day repeat rules
+// and date rolling. Holiday dates are unique.
+//
+// Implementations return an error with the text "праздник не найден"
+// when the holiday does not exist.
+type HolidayStore interface {
+	// Holidays returns holidays ordered by date; empty from/to (YYYYMMDD)
+	// mean the range is not limited on that side.
+	Holidays(from, to string) ([]*Holiday, error)
+	// GetHoliday returns a single holiday by id.
+	GetHoliday(id string) (*Holiday, error)
+	// AddHoliday inserts a new holiday and returns its ID.
+	AddHoliday(holiday *Holiday) (int64, error)
+	// UpdateHoliday replaces the date and title of a holiday.
+	UpdateHoliday(holiday *Holiday) error
+	// DeleteHoliday removes a holiday by id.
+	DeleteHoliday(id string) error
+	// ImportHolidays adds holidays at once; existing dates keep their records but get the new title.
+	ImportHolidays(holidays []*Holiday) error
+	// HolidayDates returns the set of all holiday dates (YYYYMMDD).
+	HolidayDates() (map[string]bool, error)
+}
+
+// AuditStore keeps the append-only log of task changes.
+//
+// The actor of an entry is whatever name the caller passed to As: the store does not
+// authenticate it (the API records the name self-declared at sign-in).
+// Every write changing a task (its fields, tags, checklist, dependencies, project
+// or trash state) records an entry atomically with the change, with the task as it
+// was before and after it. Writes to the tag, project and holiday dictionaries that
+// do not change any task are not logged.
+type AuditStore interface {
+	// As returns a view of the store recording its writes under the given actor;
+	// writes through the store itself are recorded under SystemActor.
+	As(actor string) TaskStore
+	// AuditLog returns the entries matching the filter, the latest first.
+	AuditLog(filter AuditFilter) ([]*AuditEntry, error)
+}
+
+// UndoStore reverts actions recorded for undo, each as a single transaction.
+//
+// A revert is conditional on the version the action left the task at: if the task
+// has changed since, nothing is changed and an error with the text "версия задачи устарела"
+// is returned ("задача не найдена" if it no longer exists, in the trash or not).
+// Links to tasks deleted since, or closing a cycle now, are not restored.
+type UndoStore interface {
+	// RevertAdd deletes an added task permanently, whether it is in the trash or not.
+	RevertAdd(id string, version int64) error
+	// RevertDelete brings a deleted task back from the trash as it was (old) together with
+	// its blockers and the links of dependents, the tasks it blocked.
+	RevertDelete(old *Task, dependents []string, version int64) error
+	// RevertDone puts back a done task as it was (old), from the trash if the done ended
+	// its series, with its tags, checklist and links like RevertDelete, and removes
+	// the completion from the history.
+	RevertDone(old *Task, dependents []string, completionID string, version int64) error
+}
+
+// SQLStore is a TaskStore backed by the scheduler table in SQLite or Postgres.
+// actor is recorded in the audit log for writes (see As).
+type SQLStore struct {
+	db      *sql.DB
+	dialect dialect
+	actor   string
+}
+
+// NewSQLStore returns a TaskStore over an opened connection of the given driver
+// (DriverSQLite or DriverPostgres).
+func NewSQLStore(db *sql.DB, driver string) *SQLStore {
+	return &SQLStore{db: db, dialect: dialect(driver)}
+}
+
+// transaction runs fn in a transaction committed if fn succeeds.
+func (s *SQLStore) transaction(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,163 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Tag is a label attached to any number of tasks.
+//
+// Name is unique; Tasks is the number of tasks with the tag (set by Tags only).
+type Tag struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Tasks int    `json:"tasks"`
+}
+
+// Tags returns all tags ordered by name, with the number of tasks (not in the trash) for each.
+func (s *SQLStore) Tags() ([]*Tag, error) {
+	rows, err := s.db.Query(`SELECT tags.id, tags.name, COUNT(scheduler.id)
+		FROM tags LEFT JOIN task_tags ON task_tags.tag_id = tags.id
+		LEFT JOIN scheduler ON scheduler.id = task_tags.task_id AND scheduler.deleted_at IS NULL
+		GROUP BY tags.id, tags.name ORDER BY tags.name`)
+	if err != nil {
+		return []*Tag{}, err
+	}
+	defer rows.Close()
+
+	tags := []*Tag{}
+	for rows.Next() {
+		t := &Tag{}
+		if err := rows.Scan(&t.ID, &t.Name, &t.Tasks); err != nil {
+			return []*Tag{}, err
+		}
+		tags = append(tags, t)
+	}
+	if err := rows.Err(); err != nil {
+		return []*Tag{}, err
+	}
+	return tags, nil
+}
+
+// AddTag creates a tag and returns its auto-generated database ID.
+func (s *SQLStore) AddTag(name string) (int64, error) {
+	if err := s.checkTagName(name, ""); err != nil {
+		return 0, err
+	}
+	return s.dialect.insert(s.db, "INSERT INTO tags (name) VALUES (?)", name)
+}
+
+// RenameTag changes the name of an existing tag.
+func (s *SQLStore) RenameTag(tag *Tag) error {
+	if err := s.checkTagName(tag.Name, tag.ID); err != nil {
+		return err
+	}
+	return s.changeTag(tag.ID, "UPDATE tags SET name = ? WHERE id = ?", tag.Name, tag.ID)
+}
+
+// DeleteTag deletes a tag; its links to tasks are removed by the database.
+func (s *SQLStore) DeleteTag(id string) error {
+	return s.changeTag(id, "DELETE FROM tags WHERE id = ?", id)
+}
+
+// changeTag runs a statement changing the tag with the given id in a transaction,
+// bumping the version of every task with the tag and recording its change in the audit log.
+func (s *SQLStore) changeTag(id, query string, args ...any) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		ids, err := s.taskIDs(tx, "SELECT task_id FROM task_tags WHERE tag_id = ? ORDER BY task_id", id)
+		if err != nil {
+			return err
+		}
+		return s.track(tx, "tag", ids, func() error {
+			_, err := tx.Exec(s.dialect.rebind("UPDATE scheduler SET version = version + 1 WHERE id IN (SELECT task_id FROM task_tags WHERE tag_id = ?)"), id)
+			if err != nil {
+				return err
+			}
+			res, err := tx.Exec(s.dialect.rebind(query), args...)
+			if err != nil {
+				return err
+			}
+
+			rowsAffected, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if rowsAffected == 0 {
+				return fmt.Errorf("тег не найден")
+			}
+			return nil
+		})
+	})
+}
+
+// checkTagName reports an error if another tag (not the one with exceptID) already has the name.
+func (s *SQLStore) checkTagName(name, exceptID string) error {
+	var id string
+	err := s.db.QueryRow(s.dialect.rebind("SELECT id FROM tags WHERE name = ?"), name).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if id != exceptID {
+		return fmt.Errorf("тег уже существует")
+	}
+	return nil
+}
+
+// setTaskTags replaces the tags of a task, creating missing tags by name.
+func (s *SQLStore) setTaskTags(tx execer, taskID int64, names []string) error {
+	if _, err := tx.Exec(s.dialect.rebind("DELETE FROM task_tags WHERE task_id = ?"), taskID); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, err := tx.Exec(s.dialect.rebind("INSERT INTO tags (name) VALUES (?) ON CONFLICT (name) DO NOTHING"), name); err != nil {
+			return err
+		}
+		var tagID int64
+		if err := tx.QueryRow(s.dialect.rebind("SELECT id FROM tags WHERE name = ?"), name).Scan(&tagID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(s.dialect.rebind(
+			"INSERT INTO task_tags (task_id, tag_id) VALUES (?, ?) ON CONFLICT DO NOTHING"), taskID, tagID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTags fills Tags of the tasks with a single query within q.
+func (s *SQLStore) loadTags(q execer, tasks []*Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	byID := make(map[string]*Task, len(tasks))
+	args := make([]any, 0, len(tasks))
+	for _, t := range tasks {
+		byID[t.ID] = t
+		args = append(args, t.ID)
+	}
+
+	query := `SELECT task_tags.task_id, tags.name FROM task_tags JOIN tags ON tags.id = task_tags.tag_id
+		WHERE task_tags.task_id IN (?` + strings.Repeat(", ?", len(tasks)-1) + `) ORDER BY tags.name`
+	rows, err := q.Query(s.dialect.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return err
+		}
+		if t, ok := byID[id]; ok {
+			t.Tags = append(t.Tags, name)
+		}
+	}
+	return rows.Err()
+}
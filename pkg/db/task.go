@@ -4,45 +4,95 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
+	"time"
 )
 
 // Task represents a single scheduled task.
 //
 // Date uses DateFormat (YYYYMMDD).
 // Repeat stores a repeat rule string (see API documentation).
+// Roll is the policy for dates falling on weekends or holidays: "", "forward" or "backward".
+// Anchor is the occurrence of the repeat rule Date was rolled from; it is empty when Date
+// is the occurrence itself. The next occurrence is computed from it, so rolling a date
+// does not shift the series. It is set by the server and ignored in requests.
+// RepeatUntil (YYYYMMDD) and RepeatCount (remaining occurrences, including the current one)
+// optionally end a repeating series; empty/zero means the series never ends.
+// Time is an optional time of day (HH:MM) and TZ an optional IANA time zone
+// (the server default is used when empty).
+// Priority is 1 (low) to 4 (urgent); 0 means no priority and is omitted from JSON.
+// ProjectID is the project of the task; it is empty (NULL in the database) for tasks without a project.
+// Tags are tag names ordered by name; they are omitted from JSON when the task has none.
+// BlockedBy lists IDs of open tasks this task depends on and Blocked is set if there are any;
+// both are omitted from JSON for tasks that are not blocked.
+// Checklist holds the task steps; it is omitted from JSON when the task has none.
+// DeletedAt (TimestampFormat) is set only for tasks in the trash.
+// Version starts at 1 and grows with every change of the task fields, tags, project or trash state;
+// an update naming a version fails if the task has changed since (see TaskStore.Update).
+// Like ID, it is a string in JSON.
+// Snippet and Rank are set only in full-text search results: a title/comment fragment
+// with matches in [brackets] and the relevance (lower is better).
 type Task struct {
-	ID      string `json:"id"`
-	Date    string `json:"date"`
-	Title   string `json:"title"`
-	Comment string `json:"comment"`
-	Repeat  string `json:"repeat"`
+	ID          string           `json:"id"`
+	Date        string           `json:"date"`
+	Title       string           `json:"title"`
+	Comment     string           `json:"comment"`
+	Repeat      string           `json:"repeat"`
+	Roll        string           `json:"roll"`
+	Anchor      string           `json:"anchor,omitempty"`
+	RepeatUntil string           `json:"repeat_until,omitempty"`
+	RepeatCount int              `json:"repeat_count,omitempty"`
+	Time        string           `json:"time,omitempty"`
+	TZ          string           `json:"tz,omitempty"`
+	Priority    int              `json:"priority,omitempty"`
+	ProjectID   string           `json:"project_id,omitempty"`
+	Tags        []string         `json:"tags,omitempty"`
+	Checklist   []*ChecklistItem `json:"checklist,omitempty"`
+	BlockedBy   []string         `json:"blocked_by,omitempty"`
+	Blocked     bool             `json:"blocked,omitempty"`
+	DeletedAt   string           `json:"deleted_at,omitempty"`
+	Version     int64            `json:"version,omitempty,string"`
+	Snippet     string           `json:"snippet,omitempty"`
+	Rank        float64          `json:"-"`
 }
 
-// AddTask inserts a new task and returns its auto-generated database ID.
-func AddTask(task *Task) (int64, error) {
-	var id int64
-	query := `INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, ?, ?)`
-	res, err := DB.Exec(query, task.Date, task.Title, task.Comment, task.Repeat)
-	if err == nil {
-		id, err = res.LastInsertId()
-	}
-	return id, err
+// taskColumns lists scheduler columns in the order expected by scanTask.
+const taskColumns = "id, date, title, comment, repeat, roll, anchor, repeat_until, repeat_count, time, tz, priority, project_id, deleted_at, version"
+
+// searchColumns are selected after taskColumns in full-text search queries.
+const searchColumns = "fts.rank, fts.snippet"
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
 }
 
-// Tasks returns latest tasks ordered by date (ascending) limited by `limit`.
-func Tasks(limit int) ([]*Task, error) {
-	rows, err := DB.Query("SELECT id, date, title, comment, repeat FROM scheduler ORDER BY date LIMIT ?", limit)
+// scanTask reads a task selected with taskColumns,
+// followed by searchColumns if search is set.
+func scanTask(row rowScanner, search bool) (*Task, error) {
+	task := &Task{}
+	var project, deleted sql.NullString
+	dest := []any{&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat, &task.Roll, &task.Anchor,
+		&task.RepeatUntil, &task.RepeatCount, &task.Time, &task.TZ, &task.Priority, &project, &deleted, &task.Version}
+	if search {
+		dest = append(dest, &task.Rank, &task.Snippet)
+	}
+	err := row.Scan(dest...)
 	if err != nil {
-		return []*Task{}, err
+		return nil, err
 	}
+	task.ProjectID = project.String
+	task.DeletedAt = deleted.String
+	return task, nil
+}
 
+// scanTasks reads all rows selected with taskColumns (and searchColumns if search is set) and closes them.
+func scanTasks(rows *sql.Rows, search bool) ([]*Task, error) {
 	defer rows.Close()
 	tasks := []*Task{}
 
 	for rows.Next() {
-		task := &Task{}
-		err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
-
+		task, err := scanTask(rows, search)
 		if err != nil {
 			return nil, err
 		}
@@ -52,36 +102,114 @@ func Tasks(limit int) ([]*Task, error) {
 	if err := rows.Err(); err != nil {
 		return []*Task{}, err
 	}
-	if tasks == nil {
-		tasks = []*Task{}
+	return tasks, nil
+}
+
+// Add inserts a new task with its tags and checklist and returns its auto-generated database ID.
+func (s *SQLStore) Add(task *Task) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
 	}
+	defer tx.Rollback()
 
-	return tasks, nil
+	query := `INSERT INTO scheduler (date, title, comment, repeat, roll, anchor, repeat_until, repeat_count, time, tz, priority, project_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	id, err := s.dialect.insert(tx, query, task.Date, task.Title, task.Comment, task.Repeat, task.Roll, task.Anchor,
+		task.RepeatUntil, task.RepeatCount, task.Time, task.TZ, task.Priority, nullID(task.ProjectID))
+	if err != nil {
+		return 0, err
+	}
+	if err := s.setTaskTags(tx, id, task.Tags); err != nil {
+		return 0, err
+	}
+	taskID := strconv.FormatInt(id, 10)
+	for _, item := range task.Checklist {
+		item.TaskID = taskID
+		if _, err := s.addItem(tx, item); err != nil {
+			return 0, err
+		}
+	}
+
+	added, err := s.snapshot(tx, taskID)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.audit(tx, taskID, "add", nil, added); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
 }
 
-// GetTask returns a single task by id.
-// If the record does not exist, a "task not found" error is returned.
-func GetTask(id string) (*Task, error) {
-	task := &Task{}
-	err := DB.QueryRow("SELECT id, date, title, comment, repeat FROM scheduler WHERE id = ?", id).
-		Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
+// List returns a page of tasks matching the filter.
+func (s *SQLStore) List(filter Filter, page Page) ([]*Task, error) {
+	q := &taskQuery{dialect: s.dialect}
+	if err := filter.apply(q); err != nil {
+		return []*Task{}, err
+	}
+
+	query, args := q.build(page)
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return []*Task{}, err
+	}
+	tasks, err := scanTasks(rows, q.match != "")
+	if err != nil {
+		return []*Task{}, err
+	}
+	if err := s.loadTags(s.db, tasks); err != nil {
+		return []*Task{}, err
+	}
+	if err := s.loadChecklists(s.db, tasks); err != nil {
+		return []*Task{}, err
+	}
+	if err := s.loadBlockers(s.db, tasks); err != nil {
+		return []*Task{}, err
+	}
+	return tasks, nil
+}
 
+// Get returns a single task by id.
+// If the record does not exist or is in the trash, a "task not found" error is returned.
+func (s *SQLStore) Get(id string) (*Task, error) {
+	task, err := scanTask(s.db.QueryRow(s.dialect.rebind("SELECT "+taskColumns+" FROM scheduler WHERE id = ? AND deleted_at IS NULL"), id), false)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("task not found")
 		}
 		return nil, err
 	}
+	if err := s.loadTags(s.db, []*Task{task}); err != nil {
+		return nil, err
+	}
+	if err := s.loadChecklists(s.db, []*Task{task}); err != nil {
+		return nil, err
+	}
+	if err := s.loadBlockers(s.db, []*Task{task}); err != nil {
+		return nil, err
+	}
 
 	return task, nil
 }
 
-// UpdateTask updates an existing task by id.
-// If no rows are affected, the task is considered missing.
-func UpdateTask(task *Task) error {
-	res, err := DB.Exec(
-		"UPDATE scheduler SET date=?, title=?, comment=?, repeat=? WHERE id=?",
-		task.Date, task.Title, task.Comment, task.Repeat, task.ID,
+// Update updates an existing task by id; its tags are replaced unless task.Tags is nil.
+// If task.Version is set, the task is updated only if it still has that version.
+// On success task.Version is set to the new version of the task.
+func (s *SQLStore) Update(task *Task) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		return s.track(tx, "update", []string{task.ID}, func() error {
+			return s.update(tx, task)
+		})
+	})
+}
+
+// update replaces the fields and tags of a task within q (see Update).
+func (s *SQLStore) update(q execer, task *Task) error {
+	res, err := q.Exec(s.dialect.rebind(
+		"UPDATE scheduler SET date=?, title=?, comment=?, repeat=?, roll=?, anchor=?, repeat_until=?, repeat_count=?, time=?, tz=?, priority=?, project_id=?, version = version + 1 "+
+			"WHERE id=? AND deleted_at IS NULL AND (? = 0 OR version = ?)"),
+		task.Date, task.Title, task.Comment, task.Repeat, task.Roll, task.Anchor, task.RepeatUntil, task.RepeatCount,
+		task.Time, task.TZ, task.Priority, nullID(task.ProjectID), task.ID, task.Version, task.Version,
 	)
 	if err != nil {
 		return err
@@ -92,16 +220,61 @@ func UpdateTask(task *Task) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("задача не найдена")
+		return s.missingOrStale(q, task.ID)
 	}
 
-	return nil
+	if task.Tags != nil {
+		id, err := strconv.ParseInt(task.ID, 10, 64)
+		if err != nil {
+			return fmt.Errorf("задача не найдена")
+		}
+		if err := s.setTaskTags(q, id, task.Tags); err != nil {
+			return err
+		}
+	}
+	return q.QueryRow(s.dialect.rebind("SELECT version FROM scheduler WHERE id = ?"), task.ID).Scan(&task.Version)
+}
+
+// missingOrStale returns the error for a write conditional on the task version that
+// changed nothing within q: "версия задачи устарела" if the task is there, otherwise
+// "задача не найдена".
+func (s *SQLStore) missingOrStale(q execer, id string) error {
+	var found int
+	err := q.QueryRow(s.dialect.rebind("SELECT COUNT(*) FROM scheduler WHERE id = ? AND deleted_at IS NULL"), id).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if found > 0 {
+		return fmt.Errorf("версия задачи устарела")
+	}
+	return fmt.Errorf("задача не найдена")
 }
 
-// DeleteTask removes a task by id.
+// Delete moves a task to the trash.
 // If no rows are affected, the task is considered missing.
-func DeleteTask(id string) error {
-	res, err := DB.Exec("DELETE FROM scheduler WHERE id=?", id)
+func (s *SQLStore) Delete(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = s.track(tx, "delete", []string{id}, func() error {
+		return s.trackDependents(tx, []string{id}, func() error {
+			return s.trashTask(tx, id, 0, time.Now())
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// trashTask moves a task to the trash within q, dropping its dependencies in both directions.
+// A non-zero version must match the version of the task (see missingOrStale).
+func (s *SQLStore) trashTask(q execer, id string, version int64, at time.Time) error {
+	res, err := q.Exec(s.dialect.rebind("UPDATE scheduler SET deleted_at = ?, version = version + 1 WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)"),
+		at.UTC().Format(TimestampFormat), id, version, version)
 	if err != nil {
 		return err
 	}
@@ -111,15 +284,42 @@ func DeleteTask(id string) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("задача не найдена")
+		return s.missingOrStale(q, id)
+	}
+	_, err = q.Exec(s.dialect.rebind("DELETE FROM task_dependencies WHERE task_id = ? OR blocker_id = ?"), id, id)
+	return err
+}
+
+// UpdateDate moves a task to the next date (an occurrence that is not rolled), consumes one
+// occurrence of repeat_count (if set), resets the task checklist for the new occurrence
+// and unblocks the tasks depending on it.
+func (s *SQLStore) UpdateDate(next string, id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = s.track(tx, "reschedule", []string{id}, func() error {
+		return s.trackDependents(tx, []string{id}, func() error {
+			return s.updateDate(tx, next, "", id, 0)
+		})
+	})
+	if err != nil {
+		return err
 	}
-	return nil
+	return tx.Commit()
 }
 
-// UpdateDate updates only the date field for a task.
-// Used when marking repeating tasks as done.
-func UpdateDate(next string, id string) error {
-	res, err := DB.Exec("UPDATE scheduler SET date=? WHERE id=?", next, id)
+// updateDate moves a task to the next date within q (see UpdateDate);
+// anchor is the occurrence next was rolled from (see Task.Anchor).
+// A non-zero version must match the version of the task (see missingOrStale).
+func (s *SQLStore) updateDate(q execer, next, anchor string, id string, version int64) error {
+	res, err := q.Exec(s.dialect.rebind(
+		"UPDATE scheduler SET date=?, anchor=?, repeat_count = CASE WHEN repeat_count > 0 THEN repeat_count - 1 ELSE 0 END, version = version + 1 "+
+			"WHERE id=? AND deleted_at IS NULL AND (? = 0 OR version = ?)"),
+		next, anchor, id, version, version,
+	)
 	if err != nil {
 		return err
 	}
@@ -129,8 +329,11 @@ func UpdateDate(next string, id string) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("задача не найдена")
+		return s.missingOrStale(q, id)
 	}
 
-	return nil
+	if err := s.resetChecklist(q, id); err != nil {
+		return err
+	}
+	return s.unblockDependents(q, id)
 }
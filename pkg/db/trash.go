@@ -0,0 +1,108 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Trash returns the tasks in the trash, the most recently deleted first.
+func (s *SQLStore) Trash() ([]*Task, error) {
+	rows, err := s.db.Query("SELECT " + taskColumns + " FROM scheduler WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC")
+	if err != nil {
+		return []*Task{}, err
+	}
+	tasks, err := scanTasks(rows, false)
+	if err != nil {
+		return []*Task{}, err
+	}
+	if err := s.loadTags(s.db, tasks); err != nil {
+		return []*Task{}, err
+	}
+	if err := s.loadChecklists(s.db, tasks); err != nil {
+		return []*Task{}, err
+	}
+	return tasks, nil
+}
+
+// Restore moves a task from the trash back to the task list.
+func (s *SQLStore) Restore(id string) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		return s.track(tx, "restore", []string{id}, func() error {
+			res, err := tx.Exec(s.dialect.rebind("UPDATE scheduler SET deleted_at = NULL, version = version + 1 WHERE id = ? AND deleted_at IS NOT NULL"), id)
+			if err != nil {
+				return err
+			}
+			return trashAffected(res)
+		})
+	})
+}
+
+// Purge deletes a task in the trash permanently.
+func (s *SQLStore) Purge(id string) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		return s.track(tx, "purge", []string{id}, func() error {
+			res, err := tx.Exec(s.dialect.rebind("DELETE FROM scheduler WHERE id = ? AND deleted_at IS NOT NULL"), id)
+			if err != nil {
+				return err
+			}
+			return trashAffected(res)
+		})
+	})
+}
+
+// PurgeTrash permanently deletes the tasks moved to the trash before the given time
+// (all of them if before is zero) and returns their number.
+func (s *SQLStore) PurgeTrash(before time.Time) (int64, error) {
+	where := " WHERE deleted_at IS NOT NULL"
+	var args []any
+	if !before.IsZero() {
+		where += " AND deleted_at < ?"
+		args = append(args, before.UTC().Format(TimestampFormat))
+	}
+
+	var n int64
+	err := s.transaction(func(tx *sql.Tx) error {
+		ids, err := s.taskIDs(tx, "SELECT id FROM scheduler"+where+" ORDER BY id", args...)
+		if err != nil {
+			return err
+		}
+		return s.track(tx, "purge", ids, func() error {
+			res, err := tx.Exec(s.dialect.rebind("DELETE FROM scheduler"+where), args...)
+			if err != nil {
+				return err
+			}
+			n, err = res.RowsAffected()
+			return err
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// trashProject moves the tasks of a project to the trash within q and takes them out of the project.
+func (s *SQLStore) trashProject(q execer, projectID string) error {
+	now := time.Now().UTC().Format(TimestampFormat)
+	_, err := q.Exec(s.dialect.rebind(`DELETE FROM task_dependencies WHERE task_id IN (SELECT id FROM scheduler WHERE project_id = ? AND deleted_at IS NULL)
+		OR blocker_id IN (SELECT id FROM scheduler WHERE project_id = ? AND deleted_at IS NULL)`), projectID, projectID)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(s.dialect.rebind("UPDATE scheduler SET deleted_at = COALESCE(deleted_at, ?), project_id = NULL, version = version + 1 WHERE project_id = ?"),
+		now, projectID)
+	return err
+}
+
+// trashAffected reports a task missing from the trash if the statement changed no rows.
+func trashAffected(res sql.Result) error {
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("задача не найдена в корзине")
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// RevertAdd deletes permanently a task still at the version it was added with.
+func (s *SQLStore) RevertAdd(id string, version int64) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		return s.track(tx, "undo", []string{id}, func() error {
+			return s.trackDependents(tx, []string{id}, func() error {
+				res, err := tx.Exec(s.dialect.rebind("DELETE FROM scheduler WHERE id = ? AND version = ?"), id, version)
+				if err != nil {
+					return err
+				}
+				return s.revertAffected(tx, res, id)
+			})
+		})
+	})
+}
+
+// RevertDelete brings a deleted task back from the trash with its fields and links.
+func (s *SQLStore) RevertDelete(old *Task, dependents []string, version int64) error {
+	return s.revert(old, dependents, "", version)
+}
+
+// RevertDone puts back a done task with its fields, checklist and links and removes its completion.
+func (s *SQLStore) RevertDone(old *Task, dependents []string, completionID string, version int64) error {
+	return s.revert(old, dependents, completionID, version)
+}
+
+// revert writes back a task snapshot taken before an action in a single transaction,
+// provided the task still has the version the action left it at (see UndoStore).
+// completionID, if set, is removed from the history.
+func (s *SQLStore) revert(old *Task, dependents []string, completionID string, version int64) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		return s.track(tx, "undo", []string{old.ID}, func() error {
+			return s.track(tx, "link", dependents, func() error {
+				// The version is checked here and incremented once by update below.
+				res, err := tx.Exec(s.dialect.rebind("UPDATE scheduler SET deleted_at = NULL WHERE id = ? AND version = ?"),
+					old.ID, version)
+				if err != nil {
+					return err
+				}
+				if err := s.revertAffected(tx, res, old.ID); err != nil {
+					return err
+				}
+
+				t := *old
+				t.Version = 0
+				if t.Tags == nil {
+					t.Tags = []string{}
+				}
+				if err := s.update(tx, &t); err != nil {
+					return err
+				}
+				for _, item := range old.Checklist {
+					_, err := tx.Exec(s.dialect.rebind("UPDATE checklist_items SET title = ?, done = ?, required = ? WHERE id = ? AND task_id = ?"),
+						item.Title, item.Done, item.Required, item.ID, old.ID)
+					if err != nil {
+						return err
+					}
+				}
+				err = relink(old, dependents, func(taskID, blockerID string) error {
+					task, blocker, err := parseLink(taskID, blockerID)
+					if err != nil {
+						return err
+					}
+					return s.link(tx, task, blocker)
+				})
+				if err != nil || completionID == "" {
+					return err
+				}
+				_, err = tx.Exec(s.dialect.rebind("DELETE FROM completions WHERE id = ?"), completionID)
+				return err
+			})
+		})
+	})
+}
+
+// revertAffected returns the error of a revert conditional on the task version that
+// changed nothing within q: "версия задачи устарела" if the task is there (in the trash
+// or not), otherwise "задача не найдена".
+func (s *SQLStore) revertAffected(q execer, res sql.Result, id string) error {
+	rowsAffected, err := res.RowsAffected()
+	if err != nil || rowsAffected > 0 {
+		return err
+	}
+	var found int
+	if err := q.QueryRow(s.dialect.rebind("SELECT COUNT(*) FROM scheduler WHERE id = ?"), id).Scan(&found); err != nil {
+		return err
+	}
+	if found > 0 {
+		return fmt.Errorf("версия задачи устарела")
+	}
+	return fmt.Errorf("задача не найдена")
+}
+
+// relink restores the dependencies of a task with the given link function: its blockers
+// and the links of the tasks it blocked. Links to tasks deleted since then,
+// or closing a cycle now, are skipped.
+func relink(old *Task, dependents []string, link func(taskID, blockerID string) error) error {
+	skip := func(err error) error {
+		if err != nil && err.Error() != "задача не найдена" && err.Error() != "цикл зависимостей" {
+			return err
+		}
+		return nil
+	}
+	for _, blocker := range old.BlockedBy {
+		if err := skip(link(old.ID, blocker)); err != nil {
+			return err
+		}
+	}
+	for _, dependent := range dependents {
+		if err := skip(link(dependent, old.ID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -10,17 +10,25 @@ import (
 	"time"
 
 	"github.com/MaximK0valev/go-task-scheduler/pkg/api"
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
 )
 
-// Run starts the HTTP server, registers API routes and serves static web files.
+// Run starts the HTTP server, registers API routes over the task store and serves static web files.
 //
 // The server supports graceful shutdown on SIGINT/SIGTERM.
-func Run() {
+func Run(tasks db.TaskStore) {
 	config := api.GetConfig()
 	port := config.TodoPort
 
 	// Register HTTP handlers under /api/*.
-	api.Init()
+	api.Init(tasks)
+
+	// Purge tasks kept in the trash longer than TODO_TRASH_DAYS.
+	stopPurge := make(chan struct{})
+	defer close(stopPurge)
+	if retention, err := config.TrashRetention(); err == nil && retention > 0 {
+		go api.PurgeExpiredTrash(retention, stopPurge)
+	}
 
 	// Serve static UI from ./web (login page, index, assets).
 	webDir := "./web"
@@ -91,6 +91,8 @@ func TestAddTask(t *testing.T) {
 		{"28.01.2024", "Заголовок", "", ""},
 		{"20240112", "Заголовок", "", "w"},
 		{"20240212", "Заголовок", "", "ooops"},
+		{"20240212", "Заголовок", "", "d 401"},
+		{"20240212", "Заголовок", "", "m -2,-3"},
 	}
 	for _, v := range tbl {
 		m, err := postJSON("api/task", map[string]any{
@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/api"
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAudit(t *testing.T) {
+	forEachStore(t, testAudit)
+
+	// The SQLite log is append-only.
+	conn := openSQLite(t)
+	_, err := db.NewSQLStore(conn, db.DriverSQLite).Add(&db.Task{Date: time.Now().Format(`20060102`), Title: "Запись в журнале"})
+	assert.NoError(t, err)
+	_, err = conn.Exec("UPDATE audit_log SET actor = 'x'")
+	assert.Error(t, err)
+	_, err = conn.Exec("DELETE FROM audit_log")
+	assert.Error(t, err)
+}
+
+// auditEntries returns the audit log entries for a query string.
+func auditEntries(t *testing.T, m *memoryAPI, query string) []map[string]any {
+	ret := m.call(t, http.MethodGet, "/api/audit"+query, nil)
+	list, ok := ret["entries"].([]any)
+	assert.True(t, ok, ret)
+	var entries []map[string]any
+	for _, v := range list {
+		e, _ := v.(map[string]any)
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// auditActions returns "actor:action" of the audit log entries for a query string.
+func auditActions(t *testing.T, m *memoryAPI, query string) []string {
+	var actions []string
+	for _, e := range auditEntries(t, m, query) {
+		actions = append(actions, fmt.Sprint(e["actor"], ":", e["action"]))
+	}
+	return actions
+}
+
+// testAudit runs the audit log scenario against an empty store.
+func testAudit(t *testing.T, store db.TaskStore) {
+	m := newMemoryAPI(t, store)
+	defer m.srv.Close()
+	anna := &memoryAPI{srv: m.srv}
+	ret := anna.call(t, http.MethodPost, "/api/signin", map[string]any{"password": api.GetConfig().TodoPassword, "name": "Анна"})
+	anna.token, _ = ret["token"].(string)
+
+	// The self-declared name is a claim of its own, not the subject of the token.
+	claims := jwt.MapClaims{}
+	_, _, err := jwt.NewParser().ParseUnverified(anna.token, claims)
+	assert.NoError(t, err)
+	assert.Equal(t, "Анна", claims["name"])
+	assert.NotContains(t, claims, "sub")
+
+	today := time.Now().Format(`20060102`)
+	ret = m.call(t, http.MethodPost, "/api/task", map[string]any{
+		"date": today, "title": "Отчёт", "tags": []string{"работа"},
+		"checklist": []map[string]any{{"title": "Черновик"}},
+	})
+	report := fmt.Sprint(ret["id"])
+	ret = anna.call(t, http.MethodPost, "/api/task", map[string]any{"date": today, "title": "Письмо"})
+	letter := fmt.Sprint(ret["id"])
+
+	// Every change of a task is recorded with its state before and after it.
+	assert.Empty(t, anna.call(t, http.MethodPut, "/api/task", map[string]any{
+		"id": report, "date": today, "title": "Годовой отчёт", "tags": []string{"работа"},
+		"version": taskVersion(t, anna, report),
+	}))
+	ids := checklistIDs(t, m, report)
+	if !assert.Len(t, ids, 1) {
+		return
+	}
+	m.call(t, http.MethodPost, "/api/task/checklist/toggle?id="+ids[0], nil)
+	assert.Empty(t, m.call(t, http.MethodPost, "/api/task/dependencies", map[string]any{"task_id": letter, "blocker_id": report}))
+	assert.Empty(t, anna.call(t, http.MethodPost, "/api/task/done?id="+report, nil))
+
+	assert.Equal(t, []string{"Анна:done", "user:checklist", "Анна:update", "user:add"},
+		auditActions(t, m, "?task_id="+report))
+	assert.Equal(t, []string{"Анна:unlink", "user:link", "Анна:add"}, auditActions(t, m, "?task_id="+letter))
+
+	entries := auditEntries(t, m, "?task_id="+report)
+	if !assert.Len(t, entries, 4) {
+		return
+	}
+	add, update, done := entries[3], entries[2], entries[0]
+	assert.Nil(t, add["before"])
+	after, _ := add["after"].(map[string]any)
+	assert.Equal(t, "Отчёт", after["title"])
+	assert.Equal(t, []any{"работа"}, after["tags"])
+	before, _ := update["before"].(map[string]any)
+	after, _ = update["after"].(map[string]any)
+	assert.Equal(t, "Отчёт", before["title"])
+	assert.Equal(t, "Годовой отчёт", after["title"])
+	after, _ = done["after"].(map[string]any)
+	assert.NotEmpty(t, after["deleted_at"])
+	at, err := time.Parse(time.RFC3339, fmt.Sprint(done["at"]))
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), at, time.Minute)
+
+	// A no-op change is not recorded.
+	assert.Empty(t, m.call(t, http.MethodPost, "/api/task/checklist/reorder", map[string]any{"task_id": letter, "ids": []string{}}))
+	assert.Len(t, auditEntries(t, m, "?task_id="+letter), 3)
+
+	// Undo and scheduled cleanup are recorded too; the log outlives purged tasks.
+	ret = anna.call(t, http.MethodPost, "/api/undo", nil)
+	assert.Equal(t, "done", ret["action"])
+	assert.Empty(t, m.call(t, http.MethodDelete, "/api/task?id="+report, nil))
+	_, err = store.PurgeTrash(time.Time{})
+	assert.NoError(t, err)
+	entries = auditEntries(t, m, "?task_id="+report+"&limit=1")
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, db.SystemActor, entries[0]["actor"])
+		assert.Equal(t, "purge", entries[0]["action"])
+		assert.Nil(t, entries[0]["after"])
+	}
+	// The undo is recorded as a single entry.
+	assert.Equal(t, []string{"system:purge", "user:delete", "Анна:undo", "Анна:done"}, auditActions(t, m, "?task_id="+report)[:4])
+
+	// Filters.
+	assert.Equal(t, []string{"Анна:link", "Анна:unlink", "Анна:add"}, auditActions(t, m, "?task_id="+letter+"&actor=Анна"))
+	assert.Equal(t, []string{"user:delete"}, auditActions(t, m, "?action=delete&task_id="+report))
+	assert.Len(t, auditEntries(t, m, "?from="+today+"&to="+today), len(auditEntries(t, m, "")))
+	assert.Empty(t, auditEntries(t, m, "?from="+time.Now().AddDate(0, 0, 2).Format(`20060102`)))
+	for _, query := range []string{"?limit=0", "?limit=abc", "?from=2024"} {
+		ret = m.call(t, http.MethodGet, "/api/audit"+query, nil)
+		assert.NotEmpty(t, ret["error"], query)
+	}
+}
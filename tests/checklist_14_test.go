@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecklist(t *testing.T) {
+	forEachStore(t, testChecklist)
+}
+
+// testChecklist runs the checklist scenario against an empty store.
+func testChecklist(t *testing.T, store db.TaskStore) {
+	m := newMemoryAPI(t, store)
+	defer m.srv.Close()
+
+	now := time.Now()
+	today := now.Format(`20060102`)
+
+	ret := m.call(t, http.MethodPost, "/api/task", map[string]any{
+		"date":   today,
+		"title":  "Еженедельная уборка",
+		"repeat": "d 7",
+		"checklist": []map[string]any{
+			{"title": "Пропылесосить", "required": true},
+			{"title": "Помыть окна"},
+		},
+	})
+	id := fmt.Sprint(ret["id"])
+	assert.Equal(t, []string{"Пропылесосить:false", "Помыть окна:false"}, checklistItems(t, m, id))
+
+	ret = m.call(t, http.MethodPost, "/api/task/checklist", map[string]any{"task_id": id, "title": "Вынести мусор", "required": true})
+	trash := fmt.Sprint(ret["id"])
+	ret = m.call(t, http.MethodPost, "/api/task/checklist", map[string]any{"task_id": id, "title": " "})
+	assert.NotEmpty(t, ret["error"])
+	ret = m.call(t, http.MethodPost, "/api/task/checklist", map[string]any{"task_id": "100", "title": "Ничей"})
+	assert.NotEmpty(t, ret["error"])
+
+	ids := checklistIDs(t, m, id)
+	if !assert.Len(t, ids, 3) {
+		return
+	}
+	ret = m.call(t, http.MethodPost, "/api/task/checklist/reorder", map[string]any{
+		"task_id": id, "ids": []string{ids[2], ids[0], ids[1]},
+	})
+	assert.Empty(t, ret)
+	assert.Equal(t, []string{"Вынести мусор:false", "Пропылесосить:false", "Помыть окна:false"}, checklistItems(t, m, id))
+	for _, order := range [][]string{{ids[0], ids[1]}, {ids[0], ids[0], ids[1]}, {ids[0], ids[1], "100"}} {
+		ret = m.call(t, http.MethodPost, "/api/task/checklist/reorder", map[string]any{"task_id": id, "ids": order})
+		assert.NotEmpty(t, ret["error"], order)
+	}
+
+	// Required items block completion.
+	ret = m.call(t, http.MethodPost, "/api/task/done?id="+id, nil)
+	assert.Contains(t, ret["error"], "Пропылесосить")
+	ret = m.call(t, http.MethodPost, "/api/task/checklist/toggle?id="+ids[0], nil)
+	assert.Equal(t, true, ret["done"])
+	ret = m.call(t, http.MethodPost, "/api/task/done?id="+id, nil)
+	assert.Equal(t, "Не выполнены обязательные пункты: Вынести мусор", ret["error"])
+	ret = m.call(t, http.MethodPut, "/api/task/checklist", map[string]any{"id": trash, "title": "Вынести мусор", "required": false})
+	assert.Empty(t, ret)
+	ret = m.call(t, http.MethodPost, "/api/task/done?id="+id, nil)
+	assert.Empty(t, ret)
+
+	// The next occurrence starts with a fresh checklist.
+	ret = m.call(t, http.MethodGet, "/api/task?id="+id, nil)
+	assert.Equal(t, now.AddDate(0, 0, 7).Format(`20060102`), ret["date"])
+	assert.Equal(t, []string{"Вынести мусор:false", "Пропылесосить:false", "Помыть окна:false"}, checklistItems(t, m, id))
+
+	// Editing the task keeps its checklist.
+	ret = m.call(t, http.MethodPut, "/api/task", map[string]any{"id": id, "title": "Уборка", "repeat": "d 7", "version": taskVersion(t, m, id)})
+	assert.Empty(t, ret)
+	assert.Len(t, checklistItems(t, m, id), 3)
+
+	ret = m.call(t, http.MethodDelete, "/api/task/checklist?id="+ids[0], nil)
+	assert.Empty(t, ret)
+	ret = m.call(t, http.MethodDelete, "/api/task/checklist?id="+ids[0], nil)
+	assert.NotEmpty(t, ret["error"])
+	ret = m.call(t, http.MethodPost, "/api/task/checklist/toggle?id="+ids[0], nil)
+	assert.NotEmpty(t, ret["error"])
+	assert.Equal(t, []string{"Вынести мусор:false", "Помыть окна:false"}, checklistItems(t, m, id))
+
+	// Items go away with their task.
+	ret = m.call(t, http.MethodDelete, "/api/task?id="+id, nil)
+	assert.Empty(t, ret)
+	ret = m.call(t, http.MethodGet, "/api/task/checklist?task_id="+id, nil)
+	assert.NotEmpty(t, ret["error"])
+	ret = m.call(t, http.MethodPut, "/api/task/checklist", map[string]any{"id": trash, "title": "Вынести мусор"})
+	assert.NotEmpty(t, ret["error"])
+}
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompletions(t *testing.T) {
+	forEachStore(t, testCompletions)
+}
+
+// completionDates returns "title:date" of the completions in the response, in order.
+func completionDates(ret map[string]any) []string {
+	var dates []string
+	list, _ := ret["completions"].([]any)
+	for _, v := range list {
+		c, _ := v.(map[string]any)
+		dates = append(dates, fmt.Sprintf("%v:%v", c["title"], c["date"]))
+	}
+	return dates
+}
+
+// testCompletions runs the completion history scenario against an empty store.
+func testCompletions(t *testing.T, store db.TaskStore) {
+	m := newMemoryAPI(t, store)
+	defer m.srv.Close()
+
+	now := time.Now()
+	today := now.Format(`20060102`)
+	tomorrow := now.AddDate(0, 0, 1).Format(`20060102`)
+	yesterday := now.AddDate(0, 0, -1).Format(`20060102`)
+
+	ret := m.call(t, http.MethodPost, "/api/task", map[string]any{"date": today, "title": "Отчёт"})
+	report := fmt.Sprint(ret["id"])
+	ret = m.call(t, http.MethodPost, "/api/task", map[string]any{"date": today, "title": "Зарядка", "repeat": "d 1"})
+	workout := fmt.Sprint(ret["id"])
+
+	ret = m.call(t, http.MethodGet, "/api/task/history?id="+workout, nil)
+	assert.Equal(t, []any{}, ret["completions"])
+
+	assert.Empty(t, m.call(t, http.MethodPost, "/api/task/done?id="+workout, nil))
+	assert.Empty(t, m.call(t, http.MethodPost, "/api/task/done?id="+workout, nil))
+	assert.Empty(t, m.call(t, http.MethodPost, "/api/task/done?id="+report, nil))
+
+	// Each completion keeps the date the task was scheduled for.
+	ret = m.call(t, http.MethodGet, "/api/task/history?id="+workout, nil)
+	assert.Equal(t, []string{"Зарядка:" + tomorrow, "Зарядка:" + today}, completionDates(ret))
+	list, _ := ret["completions"].([]any)
+	if assert.Len(t, list, 2) {
+		c, _ := list[0].(map[string]any)
+		assert.Equal(t, workout, c["task_id"])
+		done, err := time.Parse(time.RFC3339, fmt.Sprint(c["completed_at"]))
+		assert.NoError(t, err)
+		assert.WithinDuration(t, now, done, time.Minute)
+	}
+
+	// The history outlives a deleted task.
+	ret = m.call(t, http.MethodGet, "/api/task?id="+report, nil)
+	assert.NotEmpty(t, ret["error"])
+	ret = m.call(t, http.MethodGet, "/api/task/history?id="+report, nil)
+	assert.Equal(t, []string{"Отчёт:" + today}, completionDates(ret))
+	ret = m.call(t, http.MethodGet, "/api/task/history", nil)
+	assert.NotEmpty(t, ret["error"])
+
+	ret = m.call(t, http.MethodGet, "/api/completions", nil)
+	assert.Equal(t, []string{"Отчёт:" + today, "Зарядка:" + tomorrow, "Зарядка:" + today}, completionDates(ret))
+	ret = m.call(t, http.MethodGet, "/api/completions?from="+yesterday+"&to="+tomorrow, nil)
+	assert.Len(t, ret["completions"], 3)
+	ret = m.call(t, http.MethodGet, "/api/completions?from="+tomorrow, nil)
+	assert.Equal(t, []any{}, ret["completions"])
+	ret = m.call(t, http.MethodGet, "/api/completions?to="+yesterday, nil)
+	assert.Equal(t, []any{}, ret["completions"])
+	ret = m.call(t, http.MethodGet, "/api/completions?from=2026-01-01", nil)
+	assert.NotEmpty(t, ret["error"])
+
+	// Completing a version read before another completion changes nothing: the occurrence is not skipped.
+	_, err := store.Complete(workout, 2, now.AddDate(0, 0, 3).Format(`20060102`), "", now)
+	if assert.Error(t, err) {
+		assert.Equal(t, "версия задачи устарела", err.Error())
+	}
+	ret = m.call(t, http.MethodGet, "/api/task?id="+workout, nil)
+	assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), ret["date"])
+
+	// Concurrent "done" requests move the task once per recorded completion.
+	var wg sync.WaitGroup
+	var done atomic.Int32
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if len(m.call(t, http.MethodPost, "/api/task/done?id="+workout, nil)) == 0 {
+				done.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Positive(t, done.Load())
+	ret = m.call(t, http.MethodGet, "/api/task?id="+workout, nil)
+	assert.Equal(t, now.AddDate(0, 0, 2+int(done.Load())).Format(`20060102`), ret["date"])
+	ret = m.call(t, http.MethodGet, "/api/task/history?id="+workout, nil)
+	assert.Len(t, ret["completions"], 2+int(done.Load()))
+}
@@ -2,9 +2,11 @@ package tests
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	_ "modernc.org/sqlite"
@@ -16,6 +18,17 @@ type Task struct {
 	Title   string `db:"title"`
 	Comment string `db:"comment"`
 	Repeat  string `db:"repeat"`
+	Roll    string `db:"roll"`
+	Anchor  string `db:"anchor"`
+
+	RepeatUntil string  `db:"repeat_until"`
+	RepeatCount int     `db:"repeat_count"`
+	Time        string  `db:"time"`
+	TZ          string  `db:"tz"`
+	Priority    int     `db:"priority"`
+	ProjectID   *int64  `db:"project_id"`
+	DeletedAt   *string `db:"deleted_at"`
+	Version     int64   `db:"version"`
 }
 
 func count(db *sqlx.DB) (int, error) {
@@ -64,3 +77,79 @@ func TestDB(t *testing.T) {
 
 	assert.Equal(t, before, after)
 }
+
+func TestMigrations(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	var versions []int
+	err := db.Select(&versions, `SELECT version FROM schema_migrations ORDER BY version`)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, versions)
+	for i, v := range versions {
+		assert.Equal(t, i+1, v)
+	}
+}
+
+// TestLegacyAdoption checks that a database created before versioned migrations
+// is adopted at the baseline and that a failed adoption leaves it untouched.
+func TestLegacyAdoption(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "legacy.db")
+	legacy, err := sqlx.Open("sqlite", file)
+	assert.NoError(t, err)
+	defer legacy.Close()
+	_, err = legacy.Exec(`
+CREATE TABLE scheduler (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    date CHAR(8) NOT NULL DEFAULT '',
+    title VARCHAR(256) NOT NULL DEFAULT '',
+    comment TEXT NOT NULL DEFAULT '',
+    repeat VARCHAR(128) NOT NULL DEFAULT ''
+);
+INSERT INTO scheduler (date, title, repeat) VALUES ('20240101', 'Старая задача', 'd 1');
+CREATE TABLE idx_scheduler_date (id INTEGER);`)
+	assert.NoError(t, err)
+
+	// The baseline script cannot create its index over a table of the same name,
+	// so adoption fails after schema_migrations has been created.
+	_, err = db.Connect(db.DriverSQLite, file)
+	assert.Error(t, err)
+	var tables int
+	assert.NoError(t, legacy.Get(&tables, `SELECT count(*) FROM sqlite_master WHERE name = 'schema_migrations'`))
+	assert.Zero(t, tables)
+
+	_, err = legacy.Exec(`DROP TABLE idx_scheduler_date`)
+	assert.NoError(t, err)
+	conn, err := db.Connect(db.DriverSQLite, file)
+	if !assert.NoError(t, err) {
+		return
+	}
+	conn.Close()
+
+	migrations, err := db.Migrations()
+	assert.NoError(t, err)
+	var versions []int
+	assert.NoError(t, legacy.Select(&versions, `SELECT version FROM schema_migrations ORDER BY version`))
+	assert.Len(t, versions, len(migrations))
+
+	var task Task
+	assert.NoError(t, legacy.Get(&task, `SELECT * FROM scheduler`))
+	assert.Equal(t, "Старая задача", task.Title)
+	assert.Equal(t, "d 1", task.Repeat)
+	assert.Equal(t, int64(1), task.Version)
+}
+
+// TestMigrationDialects checks that every dialect has the same numbered migrations.
+func TestMigrationDialects(t *testing.T) {
+	files := func(driver string) []string {
+		names, err := filepath.Glob(filepath.Join("..", "pkg", "db", "migrations", driver, "*.sql"))
+		assert.NoError(t, err)
+		for i, name := range names {
+			names[i] = filepath.Base(name)
+		}
+		return names
+	}
+	sqlite := files("sqlite")
+	assert.NotEmpty(t, sqlite)
+	assert.Equal(t, sqlite, files("postgres"))
+}
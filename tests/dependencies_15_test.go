@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependencies(t *testing.T) {
+	forEachStore(t, testDependencies)
+}
+
+// blockedTitles returns the titles of blocked tasks in the task list.
+func blockedTitles(t *testing.T, m *memoryAPI) []string {
+	ret := m.call(t, http.MethodGet, "/api/tasks", nil)
+	var titles []string
+	list, _ := ret["tasks"].([]any)
+	for _, v := range list {
+		task, _ := v.(map[string]any)
+		if task["blocked"] == true {
+			titles = append(titles, fmt.Sprint(task["title"]))
+		}
+	}
+	return titles
+}
+
+// testDependencies runs the task dependencies scenario against an empty store.
+func testDependencies(t *testing.T, store db.TaskStore) {
+	m := newMemoryAPI(t, store)
+	defer m.srv.Close()
+
+	today := time.Now().Format(`20060102`)
+	add := func(title, repeat string) string {
+		ret := m.call(t, http.MethodPost, "/api/task", map[string]any{"date": today, "title": title, "repeat": repeat})
+		return fmt.Sprint(ret["id"])
+	}
+	design := add("Дизайн", "")
+	build := add("Сборка", "")
+	release := add("Релиз", "d 1")
+	standup := add("Планёрка", "d 1")
+
+	link := func(taskID, blockerID string) map[string]any {
+		return m.call(t, http.MethodPost, "/api/task/dependencies", map[string]any{"task_id": taskID, "blocker_id": blockerID})
+	}
+	assert.Empty(t, link(build, design))
+	assert.Empty(t, link(build, design))
+	assert.Empty(t, link(release, build))
+	assert.Empty(t, link(build, standup))
+
+	// Cycles are refused, direct or through other tasks.
+	for _, pair := range [][2]string{{design, design}, {design, build}, {design, release}} {
+		ret := link(pair[0], pair[1])
+		assert.Equal(t, "Зависимость образует цикл", ret["error"], pair)
+	}
+	ret := link(build, "100")
+	assert.Equal(t, "Задача не найдена", ret["error"])
+
+	ret = m.call(t, http.MethodGet, "/api/task/dependencies?task_id="+build, nil)
+	assert.Equal(t, []any{design, standup}, ret["blocked_by"])
+	assert.Equal(t, []any{release}, ret["blocks"])
+	ret = m.call(t, http.MethodGet, "/api/task/dependencies?task_id="+design, nil)
+	assert.Equal(t, []any{}, ret["blocked_by"])
+	assert.ElementsMatch(t, []string{"Сборка", "Релиз"}, blockedTitles(t, m))
+
+	// A blocked task is not done unless forced.
+	ret = m.call(t, http.MethodPost, "/api/task/done?id="+release, nil)
+	assert.Equal(t, "Задача заблокирована задачами: "+build, ret["error"])
+	ret = m.call(t, http.MethodPost, "/api/task/done?id="+release+"&force=yes", nil)
+	assert.NotEmpty(t, ret["error"])
+	ret = m.call(t, http.MethodPost, "/api/task/done?id="+release+"&force=true", nil)
+	assert.Empty(t, ret)
+	ret = m.call(t, http.MethodGet, "/api/task?id="+release, nil)
+	assert.Equal(t, true, ret["blocked"])
+
+	// Done blockers, deleted or moved to the next date, unblock their dependents.
+	assert.Empty(t, m.call(t, http.MethodPost, "/api/task/done?id="+design, nil))
+	assert.Empty(t, m.call(t, http.MethodPost, "/api/task/done?id="+standup, nil))
+	ret = m.call(t, http.MethodGet, "/api/task?id="+build, nil)
+	assert.Nil(t, ret["blocked"])
+	assert.Nil(t, ret["blocked_by"])
+	ret = m.call(t, http.MethodGet, "/api/task/dependencies?task_id="+standup, nil)
+	assert.Equal(t, []any{}, ret["blocks"])
+
+	ret = m.call(t, http.MethodDelete, "/api/task/dependencies?task_id="+release+"&blocker_id="+build, nil)
+	assert.Empty(t, ret)
+	ret = m.call(t, http.MethodDelete, "/api/task/dependencies?task_id="+release+"&blocker_id="+build, nil)
+	assert.Equal(t, "Зависимость не найдена", ret["error"])
+	assert.Empty(t, blockedTitles(t, m))
+
+	// Links go away with their tasks.
+	assert.Empty(t, link(release, build))
+	assert.Empty(t, m.call(t, http.MethodDelete, "/api/task?id="+build, nil))
+	assert.Empty(t, blockedTitles(t, m))
+}
@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/api"
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+// forEachStore runs a scenario against every task store, each time starting from an empty one:
+// in memory and in a new SQLite database.
+func forEachStore(t *testing.T, scenario func(t *testing.T, store db.TaskStore)) {
+	t.Run("memory", func(t *testing.T) {
+		scenario(t, db.NewMemoryStore())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		scenario(t, db.NewSQLStore(openSQLite(t), db.DriverSQLite))
+	})
+}
+
+// openSQLite returns a connection to a new migrated SQLite database,
+// closed when the test ends.
+func openSQLite(t *testing.T) *sql.DB {
+	conn, err := db.Connect(db.DriverSQLite, filepath.Join(t.TempDir(), "scheduler.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// memoryAPI serves the API over the given task store in-process, without the external server.
+type memoryAPI struct {
+	srv   *httptest.Server
+	token string
+}
+
+// newMemoryAPI starts the API over a store and signs in.
+func newMemoryAPI(t *testing.T, store db.TaskStore) *memoryAPI {
+	m := &memoryAPI{srv: httptest.NewServer(api.NewMux(store))}
+	ret := m.call(t, http.MethodPost, "/api/signin", map[string]any{"password": api.GetConfig().TodoPassword})
+	if token, ok := ret["token"].(string); ok {
+		m.token = token
+	}
+	return m
+}
+
+// call sends a request with an optional JSON body and decodes the JSON response.
+func (m *memoryAPI) call(t *testing.T, method, path string, values map[string]any) map[string]any {
+	_, _, ret := m.callIfMatch(t, method, path, "", values)
+	return ret
+}
+
+// callIfMatch is like memoryAPI.call with an optional If-Match header;
+// it also returns the response status and ETag header.
+func (m *memoryAPI) callIfMatch(t *testing.T, method, path, ifMatch string, values map[string]any) (int, string, map[string]any) {
+	var data []byte
+	if values != nil {
+		var err error
+		data, err = json.Marshal(values)
+		assert.NoError(t, err)
+	}
+	req, err := http.NewRequest(method, m.srv.URL+path, bytes.NewBuffer(data))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if m.token != "" {
+		req.Header.Set("Authorization", "Bearer "+m.token)
+	}
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	var ret map[string]any
+	assert.NoError(t, json.Unmarshal(body, &ret), string(body))
+	return resp.StatusCode, resp.Header.Get("ETag"), ret
+}
+
+// taskVersion returns the current version of a task.
+func taskVersion(t *testing.T, m *memoryAPI, id string) string {
+	ret := m.call(t, http.MethodGet, "/api/task?id="+id, nil)
+	version, ok := ret["version"].(string)
+	assert.True(t, ok, ret)
+	return version
+}
+
+// listTitles returns the titles of the /api/tasks response.
+func listTitles(ret map[string]any) []string {
+	var titles []string
+	list, _ := ret["tasks"].([]any)
+	for _, v := range list {
+		task, _ := v.(map[string]any)
+		titles = append(titles, fmt.Sprint(task["title"]))
+	}
+	return titles
+}
+
+// tagNames returns the tags of a task from an API response.
+func tagNames(ret map[string]any) []string {
+	var names []string
+	list, _ := ret["tags"].([]any)
+	for _, v := range list {
+		names = append(names, fmt.Sprint(v))
+	}
+	return names
+}
+
+// tagCounts returns the number of tasks for each tag.
+func tagCounts(t *testing.T, m *memoryAPI) map[string]int {
+	ret := m.call(t, http.MethodGet, "/api/tags", nil)
+	counts := map[string]int{}
+	list, _ := ret["tags"].([]any)
+	for _, v := range list {
+		tag, _ := v.(map[string]any)
+		n, _ := tag["tasks"].(float64)
+		counts[fmt.Sprint(tag["name"])] = int(n)
+	}
+	return counts
+}
+
+// checklistItems returns the checklist of a task as "title:done" strings in order.
+func checklistItems(t *testing.T, m *memoryAPI, taskID string) []string {
+	ret := m.call(t, http.MethodGet, "/api/task/checklist?task_id="+taskID, nil)
+	var items []string
+	list, _ := ret["items"].([]any)
+	for _, v := range list {
+		item, _ := v.(map[string]any)
+		items = append(items, fmt.Sprintf("%v:%v", item["title"], item["done"]))
+	}
+	return items
+}
+
+// checklistIDs returns the IDs of the task checklist items in order.
+func checklistIDs(t *testing.T, m *memoryAPI, taskID string) []string {
+	ret := m.call(t, http.MethodGet, "/api/task?id="+taskID, nil)
+	var ids []string
+	list, _ := ret["checklist"].([]any)
+	for _, v := range list {
+		item, _ := v.(map[string]any)
+		ids = append(ids, fmt.Sprint(item["id"]))
+	}
+	return ids
+}
@@ -0,0 +1,247 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+const holidaysICal = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20240130\r\n" +
+	"DTEND;VALUE=DATE:20240201\r\n" +
+	"SUMMARY:Тестовые\r\n" +
+	"  выходные\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func importHolidays(t *testing.T, ical string) map[string]any {
+	req, err := http.NewRequest(http.MethodPost, getURL("api/holidays/import"), bytes.NewBufferString(ical))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "text/calendar")
+	if len(Token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: Token})
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m
+}
+
+func nextDateBody(t *testing.T, query string) string {
+	body, err := getBody("api/nextdate?now=20240126&" + query)
+	assert.NoError(t, err)
+	return strings.TrimSpace(string(body))
+}
+
+func TestHolidays(t *testing.T) {
+	ret, err := postJSON("api/holidays", map[string]any{
+		"date":  "20240129",
+		"title": "Тестовый праздник",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["id"])
+
+	ret, err = postJSON("api/holidays", map[string]any{
+		"date": "2024-01-29",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret = importHolidays(t, holidaysICal)
+	assert.Equal(t, float64(2), ret["imported"])
+	ret = importHolidays(t, "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n")
+	assert.NotEmpty(t, ret["error"])
+
+	body, err := requestJSON("api/holidays?from=20240101&to=20240229", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var list struct {
+		Holidays []map[string]string `json:"holidays"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &list))
+	var dates []string
+	for _, h := range list.Holidays {
+		dates = append(dates, h["date"])
+	}
+	assert.Equal(t, []string{"20240129", "20240130", "20240131"}, dates)
+	assert.Equal(t, "Тестовые выходные", list.Holidays[1]["title"])
+
+	assert.Equal(t, "20240201", nextDateBody(t, "date=20240126&repeat=b+1"))
+	assert.Equal(t, "20240205", nextDateBody(t, "date=20240126&repeat=b+3"))
+	assert.Equal(t, "20240201", nextDateBody(t, "date=20240120&repeat=d+7&roll=forward"))
+	assert.Equal(t, "20240126", nextDateBody(t, "date=20240120&repeat=d+7&roll=backward"))
+	assert.Contains(t, nextDateBody(t, "date=20240120&repeat=d+7&roll=sideways"), "error")
+	assert.Contains(t, nextDateBody(t, "date=20240120&repeat=b+0"), "error")
+
+	for _, h := range list.Holidays {
+		ret, err := postJSON(fmt.Sprintf("api/holidays?id=%s", h["id"]), nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+	ret, err = postJSON("api/holidays?id=wjhgese", nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	assert.Equal(t, "20240129", nextDateBody(t, "date=20240126&repeat=b+1"))
+
+	testRecurringHolidays(t)
+}
+
+// testRecurringHolidays imports a yearly holiday and checks that every recurrence
+// is imported, with RDATE added and EXDATE skipped, and that an unsupported RRULE is refused.
+func testRecurringHolidays(t *testing.T) {
+	ret := importHolidays(t, "BEGIN:VCALENDAR\r\n"+
+		"BEGIN:VEVENT\r\n"+
+		"DTSTART;VALUE=DATE:20200101\r\n"+
+		"DTEND;VALUE=DATE:20200103\r\n"+
+		"RRULE:FREQ=YEARLY\r\n"+
+		"EXDATE;VALUE=DATE:20230101\r\n"+
+		"RDATE;VALUE=DATE:20200601\r\n"+
+		"SUMMARY:Новогодние каникулы\r\n"+
+		"END:VEVENT\r\n"+
+		"END:VCALENDAR\r\n")
+	assert.Nil(t, ret["error"])
+
+	body, err := requestJSON("api/holidays?from=20200101&to=20251231", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var list struct {
+		Holidays []map[string]string `json:"holidays"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &list))
+	var dates []string
+	for _, h := range list.Holidays {
+		dates = append(dates, h["date"])
+	}
+	assert.Equal(t, []string{"20200101", "20200102", "20200601", "20200602", "20210101", "20210102",
+		"20220101", "20220102", "20240101", "20240102", "20250101", "20250102"}, dates)
+
+	// The series goes on past the current year.
+	next := fmt.Sprintf("%d0101", time.Now().Year()+1)
+	body, err = requestJSON("api/holidays?from="+next+"&to="+next, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "Новогодние каникулы")
+
+	ret = importHolidays(t, "BEGIN:VCALENDAR\r\n"+
+		"BEGIN:VEVENT\r\n"+
+		"DTSTART;VALUE=DATE:20200101\r\n"+
+		"RRULE:FREQ=YEARLY;BYWEEKNO=1\r\n"+
+		"END:VEVENT\r\n"+
+		"END:VCALENDAR\r\n")
+	assert.Contains(t, ret["error"], "строка 4")
+
+	body, err = requestJSON("api/holidays", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(body, &list))
+	for _, h := range list.Holidays {
+		if h["title"] == "Новогодние каникулы" {
+			ret, err := postJSON("api/holidays?id="+h["id"], nil, http.MethodDelete)
+			assert.NoError(t, err)
+			assert.Empty(t, ret)
+		}
+	}
+}
+
+func TestHolidayStore(t *testing.T) {
+	forEachStore(t, testHolidayStore)
+}
+
+// testHolidayStore checks that the holiday calendar of the served store is used by the date rules.
+func testHolidayStore(t *testing.T, store db.TaskStore) {
+	m := newMemoryAPI(t, store)
+	defer m.srv.Close()
+
+	nextDate := func(query string) string {
+		resp, err := http.Get(m.srv.URL + "/api/nextdate?now=20240126&" + query)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return string(body)
+	}
+
+	assert.Equal(t, "20240129", nextDate("date=20240126&repeat=b+1"))
+	ret := m.call(t, http.MethodPost, "/api/holidays", map[string]any{"date": "20240129", "title": "Праздник"})
+	id := fmt.Sprint(ret["id"])
+	assert.NotEmpty(t, ret["id"])
+	ret = m.call(t, http.MethodPost, "/api/holidays", map[string]any{"date": "20240129", "title": "Ещё раз"})
+	assert.NotEmpty(t, ret["error"])
+
+	assert.Equal(t, "20240130", nextDate("date=20240126&repeat=b+1"))
+	assert.Equal(t, "20240130", nextDate("date=20240121&repeat=d+8&roll=forward"))
+
+	ret = m.call(t, http.MethodPut, "/api/holidays", map[string]any{"id": id, "date": "20240130", "title": "Перенесён"})
+	assert.Empty(t, ret)
+	assert.Equal(t, "20240129", nextDate("date=20240126&repeat=b+1"))
+	assert.Equal(t, "20240131", nextDate("date=20240126&repeat=b+2"))
+
+	ret = m.call(t, http.MethodGet, "/api/holidays", nil)
+	holidays, _ := ret["holidays"].([]any)
+	assert.Len(t, holidays, 1)
+
+	ret = m.call(t, http.MethodDelete, "/api/holidays?id="+id, nil)
+	assert.Empty(t, ret)
+	ret = m.call(t, http.MethodDelete, "/api/holidays?id="+id, nil)
+	assert.Equal(t, "Праздник не найден", ret["error"])
+	assert.Equal(t, "20240130", nextDate("date=20240126&repeat=b+2"))
+
+	testRollAnchor(t, m)
+}
+
+// testRollAnchor checks that a rolled task date does not shift the series:
+// after "done" the task moves to the occurrence following the one its date was rolled from.
+func testRollAnchor(t *testing.T, m *memoryAPI) {
+	day := func(date time.Time) string { return date.Format(`20060102`) }
+	workday := func(date time.Time) time.Time {
+		for date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+			date = date.AddDate(0, 0, 1)
+		}
+		return date
+	}
+	friday := time.Now().AddDate(0, 0, 7)
+	for friday.Weekday() != time.Friday {
+		friday = friday.AddDate(0, 0, 1)
+	}
+
+	// A weekly Friday task rolled past a holiday stays on Fridays.
+	ret := m.call(t, http.MethodPost, "/api/holidays", map[string]any{"date": day(friday), "title": "Пятничный праздник"})
+	holiday := fmt.Sprint(ret["id"])
+	ret = m.call(t, http.MethodPost, "/api/task", map[string]any{"date": day(friday), "title": "Отчёт", "repeat": "d 7", "roll": "forward"})
+	id := fmt.Sprint(ret["id"])
+	ret = m.call(t, http.MethodGet, "/api/task?id="+id, nil)
+	assert.Equal(t, day(friday.AddDate(0, 0, 3)), ret["date"])
+	assert.Equal(t, day(friday), ret["anchor"])
+	assert.Empty(t, m.call(t, http.MethodPost, "/api/task/done?id="+id, nil))
+	ret = m.call(t, http.MethodGet, "/api/task?id="+id, nil)
+	assert.Equal(t, day(friday.AddDate(0, 0, 7)), ret["date"])
+	assert.Nil(t, ret["anchor"])
+
+	// A yearly task on a Saturday keeps its day of the year, also after it is saved unchanged.
+	saturday := friday.AddDate(0, 0, 1)
+	ret = m.call(t, http.MethodPost, "/api/task", map[string]any{"date": day(saturday), "title": "Годовщина", "repeat": "y", "roll": "forward"})
+	id = fmt.Sprint(ret["id"])
+	ret = m.call(t, http.MethodGet, "/api/task?id="+id, nil)
+	assert.Equal(t, day(saturday.AddDate(0, 0, 2)), ret["date"])
+	assert.Empty(t, m.call(t, http.MethodPut, "/api/task", ret))
+	ret = m.call(t, http.MethodGet, "/api/task?id="+id, nil)
+	assert.Equal(t, day(saturday.AddDate(0, 0, 2)), ret["date"])
+	assert.Equal(t, day(saturday), ret["anchor"])
+	assert.Empty(t, m.call(t, http.MethodPost, "/api/task/done?id="+id, nil))
+	ret = m.call(t, http.MethodGet, "/api/task?id="+id, nil)
+	assert.Equal(t, day(workday(saturday.AddDate(1, 0, 0))), ret["date"])
+
+	assert.Empty(t, m.call(t, http.MethodDelete, "/api/holidays?id="+holiday, nil))
+}
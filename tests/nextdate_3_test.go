@@ -36,6 +36,11 @@ func TestNextDate(t *testing.T) {
 		{"20240320", "d 401", ""},
 		{"20231225", "d 12", `20240130`},
 		{"20240228", "d 1", "20240229"},
+		{"19700101", "d 1", "20240127"},
+		{"19500105", "d 3", "20240128"},
+		{"20240126", "d 0", ""},
+		{"20240126", "d 7 1", ""},
+		{"20240126", "y 1", ""},
 	}
 	check := func() {
 		for _, v := range tbl {
@@ -74,6 +79,51 @@ func TestNextDate(t *testing.T) {
 		{"20240126", "w 7", "20240128"},
 		{"20230126", "w 4,5", "20240201"},
 		{"20230226", "w 8,4,5", ""},
+		{"20230226", "w", ""},
+		{"20230226", "w 1,,2", ""},
+		{"20240126", "m 30,31 2", ""},
+		{"20240126", "m 29 2", "20240229"},
+		{"20240126", "m 1 13", ""},
+		{"20240101", "w 1,4 /2", "20240129"},
+		{"20240110", "w 3 /3", "20240131"},
+		{"20240126", "w 1 /1", "20240129"},
+		{"20231204", "w 5,6 /4", "20240202"},
+		{"19040104", "w 1,4 /2", "20240205"},
+		{"19000101", "m -1", "20240131"},
+		{"20240101", "w 1 /0", ""},
+		{"20240101", "w 1 /53", ""},
+		{"20240101", "w 1 2", ""},
+		{"20240101", "m 2#2", "20240213"},
+		{"20240101", "m 5#-1", "20240223"},
+		{"20240126", "m 1#1 1,4,7,10", "20240401"},
+		{"20240101", "m 15,3#1", "20240207"},
+		{"20240101", "m 2#5 2", "20280229"},
+		{"20240101", "m 8#1", ""},
+		{"20240101", "m 1#6", ""},
+		{"20240101", "m 1#0", ""},
+		{"20240101", "m 1#", ""},
+	}
+	check()
+	tbl = []nextDate{
+		{"20240101", "FREQ=DAILY;INTERVAL=10", "20240131"},
+		{"20240101", "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", "20240129"},
+		{"20240101", "FREQ=MONTHLY;BYDAY=2TU", "20240213"},
+		{"20240101", "FREQ=MONTHLY;BYDAY=-1FR", "20240223"},
+		{"20240101", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "20240131"},
+		{"20240101", "FREQ=YEARLY;BYMONTH=1,4,7,10;BYDAY=1MO", "20240401"},
+		{"20240131", "FREQ=MONTHLY;BYMONTHDAY=-1", "20240229"},
+		{"20240101", "FREQ=DAILY;COUNT=5", ""},
+		{"20240101", "FREQ=DAILY;COUNT=30", "20240127"},
+		{"20240101", "FREQ=WEEKLY;UNTIL=20240131T235959Z", "20240129"},
+		{"20240101", "FREQ=WEEKLY;UNTIL=20240120", ""},
+		{"20240229", "FREQ=YEARLY", "20280229"},
+		{"20240101", "FREQ=HOURLY", ""},
+		{"20240101", "FREQ=WEEKLY;BYDAY=2MO", ""},
+		{"20240101", "FREQ=DAILY;COUNT=3;UNTIL=20240301", ""},
+		{"20240101", "FREQ=MONTHLY;BYMONTH=2;BYMONTHDAY=30,31", ""},
+		{"20240101", "FREQ=YEARLY;BYMONTH=4,6;BYMONTHDAY=-31", ""},
+		{"20240101", "FREQ=MONTHLY;BYMONTHDAY=1;BYDAY=2MO", ""},
+		{"20240101", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-29", "20240201"},
 	}
 	check()
 }
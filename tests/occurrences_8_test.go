@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type occurrences struct {
+	date   string
+	repeat string
+	from   string
+	to     string
+	limit  string
+	want   []string
+}
+
+func TestOccurrences(t *testing.T) {
+	tbl := []occurrences{
+		{"20240126", "d 7", "", "", "3", []string{"20240202", "20240209", "20240216"}},
+		{"20240101", "w 1,3", "20240126", "20240208", "", []string{"20240129", "20240131", "20240205", "20240207"}},
+		{"20240101", "w 1,4 /2", "", "", "4", []string{"20240104", "20240115", "20240118", "20240129"}},
+		{"20240131", "m -1", "", "", "4", []string{"20240229", "20240331", "20240430", "20240531"}},
+		{"20240101", "FREQ=DAILY;COUNT=4", "", "", "", []string{"20240102", "20240103", "20240104"}},
+		{"20240101", "FREQ=MONTHLY;BYDAY=-1FR", "20240601", "", "2", []string{"20240628", "20240726"}},
+		{"20240101", "y", "", "20240601", "", []string{}},
+		{"20240101", "d 1", "20500101", "", "2", []string{"20500101", "20500102"}},
+		{"99991230", "d 1", "", "", "3", []string{"99991231"}},
+		{"00010101", "d 1", "99990101", "", "2", []string{"99990101", "99990102"}},
+	}
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/occurrences?date=%s&repeat=%s&from=%s&to=%s&limit=%s",
+			v.date, url.QueryEscape(v.repeat), v.from, v.to, v.limit)
+		body, err := getBody(urlPath)
+		assert.NoError(t, err)
+
+		var dates []string
+		err = json.Unmarshal(body, &dates)
+		assert.NoError(t, err, string(body))
+		assert.Equal(t, v.want, dates, `{%q, %q, %q, %q}`, v.date, v.repeat, v.from, v.to)
+	}
+
+	for _, v := range []string{
+		"api/occurrences?date=20240101&repeat=k",
+		"api/occurrences?date=ooops&repeat=d+1",
+		"api/occurrences?date=20240101&repeat=d+1&limit=100000",
+		// Too far from the start of the series: refused instead of stepping through it.
+		"api/occurrences?date=00010101&repeat=b+1&from=99990101&limit=50",
+	} {
+		body, err := getBody(v)
+		assert.NoError(t, err)
+		var m map[string]any
+		err = json.Unmarshal(body, &m)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, m["error"], v)
+	}
+}
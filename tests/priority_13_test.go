@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/api"
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriority(t *testing.T) {
+	forEachStore(t, testSmartOrder)
+}
+
+// testSmartOrder checks task priorities and the smart order of /api/tasks against an empty store.
+func testSmartOrder(t *testing.T, store db.TaskStore) {
+	m := newMemoryAPI(t, store)
+	defer m.srv.Close()
+
+	now := time.Now().In(api.GetConfig().Location())
+	day := func(offset int) string {
+		return now.AddDate(0, 0, offset).Format(`20060102`)
+	}
+
+	// Overdue tasks cannot be created through the API, so the store is filled directly.
+	for _, task := range []db.Task{
+		{Title: "Просрочено, низкий", Date: day(-3), Priority: 1},
+		{Title: "Просрочено, срочно", Date: day(-1), Priority: 4},
+		{Title: "Просрочено, без приоритета", Date: day(-5)},
+		{Title: "Сегодня, средний", Date: day(0), Priority: 2},
+		{Title: "Сегодня, срочно вечером", Date: day(0), Priority: 4, Time: "18:00"},
+		{Title: "Завтра", Date: day(1)},
+		{Title: "Сегодня, средний утром", Date: day(0), Priority: 2, Time: "09:00"},
+	} {
+		_, err := store.Add(&task)
+		assert.NoError(t, err)
+	}
+	want := []string{
+		"Просрочено, срочно",
+		"Просрочено, низкий",
+		"Просрочено, без приоритета",
+		"Сегодня, срочно вечером",
+		"Сегодня, средний",
+		"Сегодня, средний утром",
+		"Завтра",
+	}
+
+	ret := m.call(t, http.MethodGet, "/api/tasks?sort=smart", nil)
+	assert.Equal(t, want, listTitles(ret))
+
+	var paged []string
+	cursor := ""
+	for i := 0; i < len(want); i++ {
+		ret = m.call(t, http.MethodGet, "/api/tasks?sort=smart&limit=3&cursor="+cursor, nil)
+		paged = append(paged, listTitles(ret)...)
+		cursor = fmt.Sprint(ret["next_cursor"])
+		if ret["next_cursor"] == nil {
+			break
+		}
+	}
+	assert.Equal(t, want, paged)
+
+	tasks, err := store.List(db.Filter{}, db.Page{Limit: 10, Sort: db.SortID})
+	assert.NoError(t, err)
+	db.SmartOrder(tasks, day(0))
+	var titles []string
+	for _, task := range tasks {
+		titles = append(titles, task.Title)
+	}
+	assert.Equal(t, want, titles)
+
+	ret = m.call(t, http.MethodPost, "/api/task", map[string]any{"title": "Важное", "priority": 3})
+	id := fmt.Sprint(ret["id"])
+	ret = m.call(t, http.MethodGet, "/api/task?id="+id, nil)
+	assert.Equal(t, float64(3), ret["priority"])
+	ret = m.call(t, http.MethodPut, "/api/task", map[string]any{"id": id, "title": "Важное", "priority": 0, "version": taskVersion(t, m, id)})
+	assert.Empty(t, ret)
+	ret = m.call(t, http.MethodGet, "/api/task?id="+id, nil)
+	assert.Nil(t, ret["priority"])
+
+	for _, priority := range []int{-1, 5} {
+		ret = m.call(t, http.MethodPost, "/api/task", map[string]any{"title": "Кривой приоритет", "priority": priority})
+		assert.NotEmpty(t, ret["error"], priority)
+		ret = m.call(t, http.MethodPut, "/api/task", map[string]any{"id": id, "title": "Важное", "priority": priority, "version": taskVersion(t, m, id)})
+		assert.NotEmpty(t, ret["error"], priority)
+	}
+}
@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjects(t *testing.T) {
+	forEachStore(t, testProjects)
+}
+
+// testProjects runs the projects scenario against an empty store.
+func testProjects(t *testing.T, store db.TaskStore) {
+	m := newMemoryAPI(t, store)
+	defer m.srv.Close()
+
+	ret := m.call(t, http.MethodPost, "/api/projects", map[string]any{"name": "Работа", "color": "#1E90FF"})
+	work := fmt.Sprint(ret["id"])
+	ret = m.call(t, http.MethodPost, "/api/projects", map[string]any{"name": "Дом"})
+	home := fmt.Sprint(ret["id"])
+	for _, v := range []map[string]any{
+		{"name": " "},
+		{"name": "Цвет", "color": "red"},
+	} {
+		ret = m.call(t, http.MethodPost, "/api/projects", v)
+		assert.NotEmpty(t, ret["error"], v)
+	}
+
+	ret = m.call(t, http.MethodPost, "/api/task", map[string]any{"title": "Отчёт", "project_id": work})
+	report := fmt.Sprint(ret["id"])
+	ret = m.call(t, http.MethodPost, "/api/task", map[string]any{"title": "Созвон", "project_id": work})
+	call := fmt.Sprint(ret["id"])
+	ret = m.call(t, http.MethodPost, "/api/task", map[string]any{"title": "Уборка", "project_id": home})
+	cleaning := fmt.Sprint(ret["id"])
+	m.call(t, http.MethodPost, "/api/task", map[string]any{"title": "Без проекта"})
+	ret = m.call(t, http.MethodPost, "/api/task", map[string]any{"title": "Чужой проект", "project_id": "100"})
+	assert.NotEmpty(t, ret["error"])
+
+	ret = m.call(t, http.MethodGet, "/api/task?id="+report, nil)
+	assert.Equal(t, work, ret["project_id"])
+	ret = m.call(t, http.MethodGet, "/api/projects?id="+work, nil)
+	assert.Equal(t, "Работа", ret["name"])
+	assert.Equal(t, "#1e90ff", ret["color"])
+	assert.Equal(t, float64(2), ret["tasks"])
+
+	ret = m.call(t, http.MethodGet, "/api/tasks?project="+work, nil)
+	assert.ElementsMatch(t, []string{"Отчёт", "Созвон"}, listTitles(ret))
+	ret = m.call(t, http.MethodGet, "/api/tasks?project=none", nil)
+	assert.Equal(t, []string{"Без проекта"}, listTitles(ret))
+	ret = m.call(t, http.MethodGet, "/api/tasks?project=abc", nil)
+	assert.NotEmpty(t, ret["error"])
+
+	// Moving between projects.
+	ret = m.call(t, http.MethodPost, "/api/task/move?id="+call+"&project="+home, nil)
+	assert.Empty(t, ret)
+	ret = m.call(t, http.MethodGet, "/api/tasks?project="+home, nil)
+	assert.ElementsMatch(t, []string{"Уборка", "Созвон"}, listTitles(ret))
+	ret = m.call(t, http.MethodPost, "/api/task/move?id="+call+"&project=", nil)
+	assert.Empty(t, ret)
+	ret = m.call(t, http.MethodGet, "/api/task?id="+call, nil)
+	assert.Nil(t, ret["project_id"])
+	ret = m.call(t, http.MethodPost, "/api/task/move?id="+call+"&project=100", nil)
+	assert.NotEmpty(t, ret["error"])
+
+	// If-Match makes the move conditional on the task version.
+	version := taskVersion(t, m, call)
+	status, etag, ret := m.callIfMatch(t, http.MethodPost, "/api/task/move?id="+call+"&project="+home, `"`+version+`"`, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, ret)
+	assert.Equal(t, `"`+taskVersion(t, m, call)+`"`, etag)
+	status, _, ret = m.callIfMatch(t, http.MethodPost, "/api/task/move?id="+call+"&project=", `"`+version+`"`, nil)
+	assert.Equal(t, http.StatusPreconditionFailed, status)
+	current, _ := ret["task"].(map[string]any)
+	assert.Equal(t, home, current["project_id"])
+	status, _, _ = m.callIfMatch(t, http.MethodPost, "/api/task/move?id="+call+"&project=", "abc", nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	m.call(t, http.MethodPut, "/api/task", map[string]any{"id": call, "title": "Созвон", "project_id": work, "version": taskVersion(t, m, call)})
+	ret = m.call(t, http.MethodGet, "/api/task?id="+call, nil)
+	assert.Equal(t, work, ret["project_id"])
+
+	// Tasks of archived projects are hidden from the general list.
+	ret = m.call(t, http.MethodPut, "/api/projects", map[string]any{"id": home, "name": "Дом", "archived": true})
+	assert.Empty(t, ret)
+	ret = m.call(t, http.MethodGet, "/api/tasks", nil)
+	assert.ElementsMatch(t, []string{"Отчёт", "Созвон", "Без проекта"}, listTitles(ret))
+	ret = m.call(t, http.MethodGet, "/api/tasks?archived=true", nil)
+	assert.Len(t, ret["tasks"], 4)
+	ret = m.call(t, http.MethodGet, "/api/tasks?project="+home, nil)
+	assert.Equal(t, []string{"Уборка"}, listTitles(ret))
+	ret = m.call(t, http.MethodGet, "/api/projects", nil)
+	assert.Len(t, ret["projects"], 1)
+	ret = m.call(t, http.MethodGet, "/api/projects?archived=true", nil)
+	assert.Len(t, ret["projects"], 2)
+	ret = m.call(t, http.MethodPost, "/api/task/move?id="+report+"&project="+home, nil)
+	assert.NotEmpty(t, ret["error"])
+	ret = m.call(t, http.MethodPut, "/api/task", map[string]any{"id": cleaning, "title": "Генеральная уборка", "project_id": home, "version": taskVersion(t, m, cleaning)})
+	assert.Empty(t, ret)
+
+	// Reassign moves the tasks, cascade deletes them.
+	ret = m.call(t, http.MethodDelete, "/api/projects?id="+home+"&mode=reassign&to="+home, nil)
+	assert.NotEmpty(t, ret["error"])
+	ret = m.call(t, http.MethodDelete, "/api/projects?id="+home+"&mode=drop", nil)
+	assert.NotEmpty(t, ret["error"])
+	ret = m.call(t, http.MethodDelete, "/api/projects?id="+home+"&mode=reassign&to="+work, nil)
+	assert.Empty(t, ret)
+	ret = m.call(t, http.MethodGet, "/api/task?id="+cleaning, nil)
+	assert.Equal(t, work, ret["project_id"])
+	ret = m.call(t, http.MethodDelete, "/api/projects?id="+work+"&mode=cascade", nil)
+	assert.Empty(t, ret)
+	ret = m.call(t, http.MethodGet, "/api/tasks", nil)
+	assert.Equal(t, []string{"Без проекта"}, listTitles(ret))
+	ret = m.call(t, http.MethodDelete, "/api/projects?id="+work, nil)
+	assert.NotEmpty(t, ret["error"])
+}
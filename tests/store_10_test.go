@@ -0,0 +1,290 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskStore(t *testing.T) {
+	forEachStore(t, testTaskStore)
+}
+
+func TestFullTextSearch(t *testing.T) {
+	testFullTextSearch(t, db.NewSQLStore(openSQLite(t), db.DriverSQLite))
+}
+
+// testTaskStore runs the task API scenario against an empty store.
+func testTaskStore(t *testing.T, store db.TaskStore) {
+	m := newMemoryAPI(t, store)
+	defer m.srv.Close()
+
+	now := time.Now()
+	today := now.Format(`20060102`)
+
+	ret := m.call(t, http.MethodPost, "/api/task", map[string]any{
+		"title":   "Купить молоко",
+		"comment": "в магазине у дома",
+	})
+	assert.Equal(t, "1", fmt.Sprint(ret["id"]))
+	ret = m.call(t, http.MethodPost, "/api/task", map[string]any{
+		"date":   today,
+		"title":  "Зарядка",
+		"repeat": "d 1",
+	})
+	id := fmt.Sprint(ret["id"])
+	assert.Equal(t, "2", id)
+
+	ret = m.call(t, http.MethodGet, "/api/tasks", nil)
+	assert.Len(t, ret["tasks"], 2)
+	ret = m.call(t, http.MethodGet, "/api/tasks?search=молоко", nil)
+	assert.Len(t, ret["tasks"], 1)
+	ret = m.call(t, http.MethodGet, "/api/tasks?search="+now.Format(`02.01.2006`), nil)
+	assert.Len(t, ret["tasks"], 2)
+
+	ret = m.call(t, http.MethodPost, "/api/task/done?id="+id, nil)
+	assert.Empty(t, ret)
+	ret = m.call(t, http.MethodGet, "/api/task?id="+id, nil)
+	assert.Equal(t, now.AddDate(0, 0, 1).Format(`20060102`), ret["date"])
+
+	ret = m.call(t, http.MethodPut, "/api/task", map[string]any{
+		"id":      id,
+		"date":    today,
+		"title":   "Вечерняя зарядка",
+		"version": taskVersion(t, m, id),
+	})
+	assert.Empty(t, ret)
+	ret = m.call(t, http.MethodGet, "/api/task?id="+id, nil)
+	assert.Equal(t, "Вечерняя зарядка", ret["title"])
+
+	ret = m.call(t, http.MethodDelete, "/api/task?id="+id, nil)
+	assert.Empty(t, ret)
+	ret = m.call(t, http.MethodGet, "/api/task?id="+id, nil)
+	assert.NotEmpty(t, ret["error"])
+	ret = m.call(t, http.MethodPut, "/api/task", map[string]any{"id": "100", "title": "Нет такой", "version": "1"})
+	assert.NotEmpty(t, ret["error"])
+
+	testTaskPages(t, m)
+	testTaskFilters(t, m, store)
+	testSearchLanguage(t, m)
+	testSearchWildcards(t, m, store)
+}
+
+// testTaskPages checks cursor pagination and sorting of /api/tasks.
+// The store already holds task 1 dated today.
+func testTaskPages(t *testing.T, m *memoryAPI) {
+	now := time.Now()
+	for i, title := range []string{"Дело Г", "Дело В", "Дело Б", "Дело А"} {
+		ret := m.call(t, http.MethodPost, "/api/task", map[string]any{
+			"date":  now.AddDate(0, 0, i+1).Format(`20060102`),
+			"title": title,
+		})
+		assert.Empty(t, ret["error"])
+	}
+
+	collect := func(query string) []string {
+		var titles []string
+		cursor := ""
+		for range 10 {
+			ret := m.call(t, http.MethodGet, "/api/tasks?limit=2&"+query+"&cursor="+cursor, nil)
+			tasks, _ := ret["tasks"].([]any)
+			assert.LessOrEqual(t, len(tasks), 2)
+			for _, v := range tasks {
+				titles = append(titles, v.(map[string]any)["title"].(string))
+			}
+			next, _ := ret["next_cursor"].(string)
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		return titles
+	}
+	assert.Equal(t, []string{"Купить молоко", "Дело Г", "Дело В", "Дело Б", "Дело А"}, collect(""))
+	assert.Equal(t, []string{"Дело А", "Дело Б", "Дело В", "Дело Г", "Купить молоко"}, collect("sort=id&order=desc"))
+	assert.Equal(t, []string{"Дело А", "Дело Б", "Дело В", "Дело Г"}, collect("sort=title&search=Дело"))
+
+	ret := m.call(t, http.MethodGet, "/api/tasks?limit=1", nil)
+	cursor, _ := ret["next_cursor"].(string)
+	assert.NotEmpty(t, cursor)
+	for _, query := range []string{
+		"limit=0",
+		"limit=501",
+		"sort=priority",
+		"order=up",
+		"cursor=ooops",
+		"sort=title&cursor=" + cursor,
+	} {
+		ret := m.call(t, http.MethodGet, "/api/tasks?"+query, nil)
+		assert.NotEmpty(t, ret["error"], query)
+	}
+}
+
+// testTaskFilters checks structured filters of /api/tasks over the tasks of testTaskPages.
+func testTaskFilters(t *testing.T, m *memoryAPI, store db.TaskStore) {
+	now := time.Now()
+	day := func(n int) string { return now.AddDate(0, 0, n).Format(`20060102`) }
+
+	// The API never stores past dates, so the overdue task goes directly to the store.
+	_, err := store.Add(&db.Task{Date: day(-3), Title: "Просроченное дело", Repeat: "d 1"})
+	assert.NoError(t, err)
+
+	titles := func(query string) []string {
+		ret := m.call(t, http.MethodGet, "/api/tasks?"+query, nil)
+		assert.Empty(t, ret["error"], query)
+		var titles []string
+		tasks, _ := ret["tasks"].([]any)
+		for _, v := range tasks {
+			titles = append(titles, v.(map[string]any)["title"].(string))
+		}
+		return titles
+	}
+	assert.Equal(t, []string{"Дело Г", "Дело В"}, titles("from="+day(1)+"&to="+day(2)))
+	assert.Equal(t, []string{"Просроченное дело"}, titles("repeating=true"))
+	assert.Len(t, titles("repeating=false"), 5)
+	assert.Equal(t, []string{"Просроченное дело"}, titles("overdue=true"))
+	assert.Equal(t, []string{"Купить молоко", "Дело Г"}, titles("due_within=1"))
+	assert.Equal(t, []string{"Купить молоко"}, titles("due_within=0"))
+	assert.Equal(t, []string{"Дело Б"}, titles("search=Дело&from="+day(3)+"&due_within=3"))
+	assert.Empty(t, titles("overdue=true&repeating=false"))
+
+	for _, query := range []string{
+		"from=2024-01-01",
+		"to=ooops",
+		"repeating=maybe",
+		"overdue=2",
+		"due_within=-1",
+	} {
+		ret := m.call(t, http.MethodGet, "/api/tasks?"+query, nil)
+		assert.NotEmpty(t, ret["error"], query)
+	}
+}
+
+// testFullTextSearch checks FTS5 search syntax, ranking and snippets (SQLite only).
+func testFullTextSearch(t *testing.T, store db.TaskStore) {
+	m := newMemoryAPI(t, store)
+	defer m.srv.Close()
+
+	for _, v := range []map[string]any{
+		{"title": "Квартальный отчёт", "comment": "черновик отчёта для бухгалтерии"},
+		{"title": "Отчёт по продажам", "comment": "финальная версия"},
+		{"title": "ОТЧЁТ отчёт отчёт", "comment": ""},
+	} {
+		ret := m.call(t, http.MethodPost, "/api/task", v)
+		assert.Empty(t, ret["error"])
+	}
+
+	search := func(query string) []map[string]any {
+		ret := m.call(t, http.MethodGet, "/api/tasks?search="+url.QueryEscape(query), nil)
+		assert.Empty(t, ret["error"], query)
+		var tasks []map[string]any
+		list, _ := ret["tasks"].([]any)
+		for _, v := range list {
+			tasks = append(tasks, v.(map[string]any))
+		}
+		return tasks
+	}
+	titles := func(query string) []string {
+		var titles []string
+		for _, task := range search(query) {
+			titles = append(titles, task["title"].(string))
+		}
+		return titles
+	}
+
+	// Case-insensitive for Cyrillic; the most relevant task goes first.
+	tasks := search("ОТЧЁТ")
+	assert.Len(t, tasks, 3)
+	assert.Equal(t, "ОТЧЁТ отчёт отчёт", tasks[0]["title"])
+	assert.Equal(t, "[ОТЧЁТ] [отчёт] [отчёт]", tasks[0]["snippet"])
+
+	assert.Len(t, titles("отч*"), 3)
+	assert.Equal(t, []string{"Квартальный отчёт"}, titles(`"квартальный отчёт"`))
+	assert.Equal(t, []string{"Квартальный отчёт"}, titles("отчёт черновик"))
+	assert.ElementsMatch(t, []string{"Отчёт по продажам", "ОТЧЁТ отчёт отчёт"}, titles("отчёт NOT черновик"))
+	assert.ElementsMatch(t, []string{"Квартальный отчёт", "Отчёт по продажам"}, titles("бухгалтерии OR продажам"))
+	// FTS5 syntax characters inside terms are plain text.
+	assert.Equal(t, []string{"Отчёт по продажам"}, titles("продаж* (версия:"))
+
+	// Pages of ranked results do not overlap.
+	ret := m.call(t, http.MethodGet, "/api/tasks?limit=2&search=отчёт", nil)
+	first, _ := ret["tasks"].([]any)
+	assert.Len(t, first, 2)
+	ret = m.call(t, http.MethodGet, "/api/tasks?limit=2&search=отчёт&cursor="+ret["next_cursor"].(string), nil)
+	second, _ := ret["tasks"].([]any)
+	assert.Len(t, second, 1)
+
+	for _, query := range []string{`"незакрытая`, "AND отчёт", "отчёт OR", "отчёт AND OR план", "*"} {
+		ret := m.call(t, http.MethodGet, "/api/tasks?search="+url.QueryEscape(query), nil)
+		assert.NotEmpty(t, ret["error"], query)
+	}
+}
+
+// testSearchLanguage checks field qualifiers of the search query over the tasks of testTaskFilters.
+func testSearchLanguage(t *testing.T, m *memoryAPI) {
+	now := time.Now()
+	day := func(n int) string { return now.AddDate(0, 0, n).Format(`20060102`) }
+
+	titles := func(query string) []string {
+		ret := m.call(t, http.MethodGet, "/api/tasks?search="+url.QueryEscape(query), nil)
+		assert.Empty(t, ret["error"], query)
+		var titles []string
+		tasks, _ := ret["tasks"].([]any)
+		for _, v := range tasks {
+			titles = append(titles, v.(map[string]any)["title"].(string))
+		}
+		return titles
+	}
+	assert.Len(t, titles("title:дело"), 5)
+	assert.Equal(t, []string{"Просроченное дело"}, titles("title:дело repeat:d"))
+	assert.ElementsMatch(t, []string{"Дело Г", "Дело В"}, titles("title:дело -repeat:d before:"+day(3)))
+	assert.Equal(t, []string{"Дело А"}, titles("after:"+day(3)))
+	assert.Equal(t, []string{"Дело Г"}, titles("date:"+now.AddDate(0, 0, 1).Format(`02.01.2006`)))
+	assert.Equal(t, []string{"Купить молоко"}, titles(`comment:"у дома" `+now.Format(`02.01.2006`)))
+	assert.Empty(t, titles("молоко -comment:магазине"))
+	assert.Empty(t, titles("18:00"))
+	assert.Equal(t, []string{"Купить молоко"}, titles("-дело"))
+
+	for _, query := range []string{
+		"priority:1",
+		"title:",
+		"before:2026",
+		`title:"незакрытая`,
+		"repeat:d OR дело",
+	} {
+		ret := m.call(t, http.MethodGet, "/api/tasks?search="+url.QueryEscape(query), nil)
+		assert.NotEmpty(t, ret["error"], query)
+	}
+}
+
+// testSearchWildcards checks that LIKE wildcards in a search query are plain text.
+// The tasks are purged afterwards, so the store is left as it was.
+func testSearchWildcards(t *testing.T, m *memoryAPI, store db.TaskStore) {
+	var ids []string
+	for _, title := range []string{"Скидка 100%", "Скидка 1000", "План_Б", "Планаб"} {
+		id, err := store.Add(&db.Task{Date: time.Now().Format(`20060102`), Title: title})
+		assert.NoError(t, err)
+		ids = append(ids, fmt.Sprint(id))
+	}
+
+	for query, want := range map[string][]string{
+		"100%":          {"Скидка 100%"},
+		"title:100%":    {"Скидка 100%"},
+		"план_б":        {"План_Б"},
+		"comment:план_": nil,
+	} {
+		ret := m.call(t, http.MethodGet, "/api/tasks?search="+url.QueryEscape(query), nil)
+		assert.Empty(t, ret["error"], query)
+		assert.Equal(t, want, listTitles(ret), query)
+	}
+
+	for _, id := range ids {
+		assert.NoError(t, store.Delete(id))
+		assert.NoError(t, store.Purge(id))
+	}
+}
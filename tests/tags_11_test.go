@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTags(t *testing.T) {
+	forEachStore(t, testTags)
+}
+
+// testTags runs the tags scenario against an empty store.
+func testTags(t *testing.T, store db.TaskStore) {
+	m := newMemoryAPI(t, store)
+	defer m.srv.Close()
+
+	ret := m.call(t, http.MethodPost, "/api/task", map[string]any{
+		"title": "Сдать отчёт",
+		"tags":  []string{"Работа", "#срочно", "работа"},
+	})
+	report := fmt.Sprint(ret["id"])
+	ret = m.call(t, http.MethodPost, "/api/task", map[string]any{
+		"title": "Починить кран",
+		"tags":  []string{"дом", "срочно"},
+	})
+	tap := fmt.Sprint(ret["id"])
+	m.call(t, http.MethodPost, "/api/task", map[string]any{"title": "Без тегов"})
+
+	ret = m.call(t, http.MethodGet, "/api/task?id="+report, nil)
+	assert.Equal(t, []string{"работа", "срочно"}, tagNames(ret))
+
+	ret = m.call(t, http.MethodGet, "/api/tasks?tag=срочно", nil)
+	assert.ElementsMatch(t, []string{"Сдать отчёт", "Починить кран"}, listTitles(ret))
+	ret = m.call(t, http.MethodGet, "/api/tasks?tag=срочно&tag=дом", nil)
+	assert.Equal(t, []string{"Починить кран"}, listTitles(ret))
+	ret = m.call(t, http.MethodGet, "/api/tasks?tag=дом&search=кран", nil)
+	assert.Equal(t, []string{"Починить кран"}, listTitles(ret))
+	ret = m.call(t, http.MethodGet, "/api/tasks?tag=отпуск", nil)
+	assert.Empty(t, ret["tasks"])
+	ret = m.call(t, http.MethodGet, "/api/tasks?tag=", nil)
+	assert.NotEmpty(t, ret["error"])
+
+	// An update without "tags" keeps them, an empty list removes them.
+	m.call(t, http.MethodPut, "/api/task", map[string]any{"id": tap, "title": "Починить кран на кухне", "version": taskVersion(t, m, tap)})
+	ret = m.call(t, http.MethodGet, "/api/task?id="+tap, nil)
+	assert.Equal(t, []string{"дом", "срочно"}, tagNames(ret))
+	m.call(t, http.MethodPut, "/api/task", map[string]any{"id": tap, "title": "Починить кран", "tags": []string{}, "version": taskVersion(t, m, tap)})
+	ret = m.call(t, http.MethodGet, "/api/task?id="+tap, nil)
+	assert.Nil(t, ret["tags"])
+
+	ret = m.call(t, http.MethodGet, "/api/tags", nil)
+	tags, _ := ret["tags"].([]any)
+	if assert.Len(t, tags, 3) {
+		home := tags[0].(map[string]any)
+		assert.Equal(t, "дом", home["name"])
+		assert.Equal(t, float64(0), home["tasks"])
+		work := tags[1].(map[string]any)
+		assert.Equal(t, "работа", work["name"])
+		assert.Equal(t, float64(1), work["tasks"])
+
+		ret = m.call(t, http.MethodPut, "/api/tags", map[string]any{"id": work["id"], "name": "Офис"})
+		assert.Empty(t, ret)
+		ret = m.call(t, http.MethodGet, "/api/task?id="+report, nil)
+		assert.Equal(t, []string{"офис", "срочно"}, tagNames(ret))
+		ret = m.call(t, http.MethodPut, "/api/tags", map[string]any{"id": work["id"], "name": "дом"})
+		assert.NotEmpty(t, ret["error"])
+
+		ret = m.call(t, http.MethodDelete, "/api/tags?id="+fmt.Sprint(work["id"]), nil)
+		assert.Empty(t, ret)
+		ret = m.call(t, http.MethodGet, "/api/task?id="+report, nil)
+		assert.Equal(t, []string{"срочно"}, tagNames(ret))
+		ret = m.call(t, http.MethodDelete, "/api/tags?id="+fmt.Sprint(work["id"]), nil)
+		assert.NotEmpty(t, ret["error"])
+	}
+
+	ret = m.call(t, http.MethodPost, "/api/tags", map[string]any{"name": "отпуск"})
+	assert.NotEmpty(t, ret["id"])
+	ret = m.call(t, http.MethodPost, "/api/tags", map[string]any{"name": "Отпуск"})
+	assert.NotEmpty(t, ret["error"])
+	for _, name := range []string{"", "два слова", "a,b"} {
+		ret = m.call(t, http.MethodPost, "/api/tags", map[string]any{"name": name})
+		assert.NotEmpty(t, ret["error"], name)
+		ret = m.call(t, http.MethodPost, "/api/task", map[string]any{"title": "Кривой тег", "tags": []string{name}})
+		assert.NotEmpty(t, ret["error"], name)
+	}
+
+	// Deleting a task removes its links, so tag counters stay correct.
+	m.call(t, http.MethodDelete, "/api/task?id="+report, nil)
+	ret = m.call(t, http.MethodGet, "/api/tags", nil)
+	tags, _ = ret["tags"].([]any)
+	for _, v := range tags {
+		assert.Equal(t, float64(0), v.(map[string]any)["tasks"], v)
+	}
+}
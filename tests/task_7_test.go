@@ -2,6 +2,7 @@ package tests
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"testing"
 	"time"
@@ -73,3 +74,169 @@ func TestDelTask(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotEmpty(t, ret)
 }
+
+func TestDoneSeriesEnd(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	tomorrow := now.AddDate(0, 0, 1).Format(`20060102`)
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date":         tomorrow,
+		"title":        "Три тренировки",
+		"repeat":       "d 1",
+		"repeat_count": 2,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	var task Task
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), task.Date)
+	assert.Equal(t, 1, task.RepeatCount)
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+
+	// RRULE COUNT is counted down in repeat_count, so the series ends after COUNT completions.
+	ret, err = postJSON("api/task", map[string]any{
+		"date":   now.AddDate(0, 0, -2).Format(`20060102`),
+		"title":  "Курс таблеток",
+		"repeat": "FREQ=DAILY;COUNT=5",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id = fmt.Sprint(ret["id"])
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, tomorrow, task.Date)
+	assert.Equal(t, "FREQ=DAILY;COUNT=5", task.Repeat)
+	assert.Equal(t, 2, task.RepeatCount)
+
+	// The rule is returned as it was sent, and saving the task back as it was read keeps the series.
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, "FREQ=DAILY;COUNT=5", ret["repeat"])
+	assert.EqualValues(t, 2, ret["repeat_count"])
+	ret, err = postJSON("api/task", ret, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, tomorrow, task.Date)
+	assert.Equal(t, "FREQ=DAILY;COUNT=5", task.Repeat)
+	assert.Equal(t, 2, task.RepeatCount)
+	for i := 0; i < 2; i++ {
+		ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+	notFoundTask(t, id)
+
+	ret, err = postJSON("api/task", map[string]any{
+		"date":         tomorrow,
+		"title":        "Полить цветы до отпуска",
+		"repeat":       "d 3",
+		"repeat_until": now.AddDate(0, 0, 5).Format(`20060102`),
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id = fmt.Sprint(ret["id"])
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 4).Format(`20060102`), task.Date)
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+
+	for _, v := range []map[string]any{
+		{"title": "Без повтора", "repeat_until": tomorrow},
+		{"title": "Без повтора", "repeat_count": 3},
+		{"title": "Кривая дата", "repeat": "d 1", "repeat_until": "ooops"},
+		{"title": "Отрицательный счётчик", "repeat": "d 1", "repeat_count": -1},
+	} {
+		ret, err = postJSON("api/task", v, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], v)
+	}
+}
+
+func TestTaskTime(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	loc, err := time.LoadLocation("Pacific/Kiritimati")
+	assert.NoError(t, err)
+	now := time.Now().In(loc)
+	today := now.Format(`20060102`)
+	past := now.AddDate(0, 0, -3).Format(`20060102`)
+
+	ret, err := postJSON("api/task", map[string]any{
+		"title": "Созвон с островами",
+		"time":  "09:30",
+		"tz":    "Pacific/Kiritimati",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+	var task Task
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, today, task.Date)
+	assert.Equal(t, "09:30", task.Time)
+	assert.Equal(t, "Pacific/Kiritimati", task.TZ)
+
+	// Today's occurrence is kept until the task time has passed.
+	due := time.Date(now.Year(), now.Month(), now.Day(), 23, 59, 0, 0, loc)
+	want := today
+	if !now.Before(due) {
+		want = now.AddDate(0, 0, 1).Format(`20060102`)
+	}
+	ret, err = postJSON("api/task", map[string]any{
+		"date":   past,
+		"title":  "Вечерняя зарядка",
+		"repeat": "d 1",
+		"time":   "23:59",
+		"tz":     "Pacific/Kiritimati",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, fmt.Sprint(ret["id"]))
+	assert.NoError(t, err)
+	assert.Equal(t, want, task.Date)
+
+	ret, err = postJSON("api/task", map[string]any{
+		"date":   past,
+		"title":  "Утренняя зарядка",
+		"repeat": "d 1",
+		"time":   "00:00",
+		"tz":     "Pacific/Kiritimati",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, fmt.Sprint(ret["id"]))
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 1).Format(`20060102`), task.Date)
+
+	for _, v := range []map[string]any{
+		{"title": "Кривое время", "time": "25:00"},
+		{"title": "Кривое время", "time": "9.30"},
+		{"title": "Кривой пояс", "tz": "Mars/Olympus"},
+	} {
+		ret, err = postJSON("api/task", v, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], v)
+	}
+
+	body, err := getBody("api/nextdate?date=20240101&repeat=d+1&tz=Mars/Olympus")
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "error")
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrash(t *testing.T) {
+	forEachStore(t, testTrash)
+}
+
+// testTrash runs the trash scenario against an empty store.
+func testTrash(t *testing.T, store db.TaskStore) {
+	m := newMemoryAPI(t, store)
+	defer m.srv.Close()
+
+	today := time.Now().Format(`20060102`)
+	add := func(task map[string]any) string {
+		task["date"] = today
+		ret := m.call(t, http.MethodPost, "/api/task", task)
+		return fmt.Sprint(ret["id"])
+	}
+	garden := add(map[string]any{"title": "Полить сад", "tags": []string{"дом"}})
+	bills := add(map[string]any{"title": "Оплатить счета"})
+	report := add(map[string]any{"title": "Отчёт"})
+	add(map[string]any{"title": "Зарядка", "repeat": "d 1"})
+	assert.Empty(t, m.call(t, http.MethodPost, "/api/task/dependencies", map[string]any{"task_id": report, "blocker_id": bills}))
+
+	// Deleted and done one-off tasks go to the trash and disappear from everywhere else.
+	assert.Empty(t, m.call(t, http.MethodDelete, "/api/task?id="+garden, nil))
+	assert.Empty(t, m.call(t, http.MethodPost, "/api/task/done?id="+bills, nil))
+	ret := m.call(t, http.MethodGet, "/api/tasks", nil)
+	assert.ElementsMatch(t, []string{"Отчёт", "Зарядка"}, listTitles(ret))
+	ret = m.call(t, http.MethodGet, "/api/tasks?search=сад", nil)
+	assert.Empty(t, listTitles(ret))
+	ret = m.call(t, http.MethodGet, "/api/task?id="+garden, nil)
+	assert.NotEmpty(t, ret["error"])
+	ret = m.call(t, http.MethodPut, "/api/task", map[string]any{"id": garden, "date": today, "title": "Полить сад", "version": "1"})
+	assert.NotEmpty(t, ret["error"])
+	ret = m.call(t, http.MethodDelete, "/api/task?id="+garden, nil)
+	assert.NotEmpty(t, ret["error"])
+	assert.Equal(t, map[string]int{"дом": 0}, tagCounts(t, m))
+	ret = m.call(t, http.MethodGet, "/api/task?id="+report, nil)
+	assert.Nil(t, ret["blocked"])
+
+	ret = m.call(t, http.MethodGet, "/api/trash", nil)
+	assert.ElementsMatch(t, []string{"Полить сад", "Оплатить счета"}, listTitles(ret))
+	list, _ := ret["tasks"].([]any)
+	for _, v := range list {
+		task, _ := v.(map[string]any)
+		deleted, err := time.Parse(time.RFC3339, fmt.Sprint(task["deleted_at"]))
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), deleted, time.Minute)
+	}
+
+	// Restored tasks come back with their tags.
+	assert.Empty(t, m.call(t, http.MethodPost, "/api/trash/restore?id="+garden, nil))
+	ret = m.call(t, http.MethodPost, "/api/trash/restore?id="+garden, nil)
+	assert.Equal(t, "Задача не найдена в корзине", ret["error"])
+	ret = m.call(t, http.MethodPost, "/api/trash/restore?id="+report, nil)
+	assert.NotEmpty(t, ret["error"])
+	ret = m.call(t, http.MethodGet, "/api/task?id="+garden, nil)
+	assert.Equal(t, []any{"дом"}, ret["tags"])
+	assert.Nil(t, ret["deleted_at"])
+	assert.Equal(t, map[string]int{"дом": 1}, tagCounts(t, m))
+
+	// Purged tasks are gone for good.
+	assert.Empty(t, m.call(t, http.MethodDelete, "/api/trash?id="+bills, nil))
+	ret = m.call(t, http.MethodDelete, "/api/trash?id="+bills, nil)
+	assert.NotEmpty(t, ret["error"])
+	ret = m.call(t, http.MethodPost, "/api/trash/restore?id="+bills, nil)
+	assert.NotEmpty(t, ret["error"])
+	ret = m.call(t, http.MethodGet, "/api/trash", nil)
+	assert.Equal(t, []any{}, ret["tasks"])
+
+	// Tasks of a project deleted with mode=cascade go to the trash without the project.
+	ret = m.call(t, http.MethodPost, "/api/projects", map[string]any{"name": "Ремонт"})
+	project := fmt.Sprint(ret["id"])
+	paint := add(map[string]any{"title": "Покрасить стены", "project_id": project})
+	assert.Empty(t, m.call(t, http.MethodDelete, "/api/projects?id="+project+"&mode=cascade", nil))
+	ret = m.call(t, http.MethodGet, "/api/trash", nil)
+	assert.Equal(t, []string{"Покрасить стены"}, listTitles(ret))
+	assert.Empty(t, m.call(t, http.MethodPost, "/api/trash/restore?id="+paint, nil))
+	ret = m.call(t, http.MethodGet, "/api/task?id="+paint, nil)
+	assert.Nil(t, ret["project_id"])
+
+	// Expired tasks are purged by age, the whole trash by DELETE without id.
+	assert.Empty(t, m.call(t, http.MethodDelete, "/api/task?id="+paint, nil))
+	n, err := store.PurgeTrash(time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+	n, err = store.PurgeTrash(time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.Empty(t, m.call(t, http.MethodDelete, "/api/task?id="+garden, nil))
+	ret = m.call(t, http.MethodDelete, "/api/trash", nil)
+	assert.Equal(t, float64(1), ret["purged"])
+	ret = m.call(t, http.MethodGet, "/api/trash", nil)
+	assert.Equal(t, []any{}, ret["tasks"])
+}
@@ -0,0 +1,200 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUndo(t *testing.T) {
+	forEachStore(t, testUndo)
+}
+
+// testUndo runs the undo scenario against an empty store.
+func testUndo(t *testing.T, store db.TaskStore) {
+	m := newMemoryAPI(t, store)
+	defer m.srv.Close()
+
+	now := time.Now()
+	today := now.Format(`20060102`)
+
+	ret := m.call(t, http.MethodPost, "/api/undo", nil)
+	assert.Equal(t, "Нет действий для отмены", ret["error"])
+
+	ret = m.call(t, http.MethodPost, "/api/task", map[string]any{
+		"date":         today,
+		"title":        "Полив",
+		"repeat":       "d 7",
+		"repeat_count": 3,
+		"tags":         []string{"дом"},
+		"checklist":    []map[string]any{{"title": "Кактус", "required": true}},
+	})
+	watering := fmt.Sprint(ret["id"])
+	ret = m.call(t, http.MethodPost, "/api/task", map[string]any{"date": today, "title": "Пересадка"})
+	repotting := fmt.Sprint(ret["id"])
+	assert.Empty(t, m.call(t, http.MethodPost, "/api/task/dependencies", map[string]any{"task_id": repotting, "blocker_id": watering}))
+	ids := checklistIDs(t, m, watering)
+	if !assert.Len(t, ids, 1) {
+		return
+	}
+	m.call(t, http.MethodPost, "/api/task/checklist/toggle?id="+ids[0], nil)
+
+	// Undoing "done" of a repeating task restores its date, counter, checklist and dependents.
+	assert.Empty(t, m.call(t, http.MethodPost, "/api/task/done?id="+watering, nil))
+	ret = m.call(t, http.MethodGet, "/api/task?id="+watering, nil)
+	assert.Equal(t, now.AddDate(0, 0, 7).Format(`20060102`), ret["date"])
+	ret = m.call(t, http.MethodPost, "/api/undo", nil)
+	assert.Equal(t, map[string]any{"action": "done", "id": watering}, ret)
+	ret = m.call(t, http.MethodGet, "/api/task?id="+watering, nil)
+	assert.Equal(t, today, ret["date"])
+	assert.Equal(t, float64(3), ret["repeat_count"])
+	assert.Equal(t, []string{"Кактус:true"}, checklistItems(t, m, watering))
+	ret = m.call(t, http.MethodGet, "/api/task/history?id="+watering, nil)
+	assert.Equal(t, []any{}, ret["completions"])
+	ret = m.call(t, http.MethodGet, "/api/task?id="+repotting, nil)
+	assert.Equal(t, []any{watering}, ret["blocked_by"])
+
+	// Update.
+	ret = m.call(t, http.MethodPut, "/api/task", map[string]any{
+		"id": watering, "date": today, "title": "Полив цветов", "repeat": "d 7", "tags": []string{},
+		"version": taskVersion(t, m, watering),
+	})
+	assert.Empty(t, ret)
+	ret = m.call(t, http.MethodPost, "/api/undo", nil)
+	assert.Equal(t, "update", ret["action"])
+	ret = m.call(t, http.MethodGet, "/api/task?id="+watering, nil)
+	assert.Equal(t, "Полив", ret["title"])
+	assert.Equal(t, []any{"дом"}, ret["tags"])
+	assert.Equal(t, float64(3), ret["repeat_count"])
+
+	// Delete.
+	assert.Empty(t, m.call(t, http.MethodDelete, "/api/task?id="+watering, nil))
+	ret = m.call(t, http.MethodPost, "/api/undo", nil)
+	assert.Equal(t, "delete", ret["action"])
+	ret = m.call(t, http.MethodGet, "/api/task?id="+repotting, nil)
+	assert.Equal(t, []any{watering}, ret["blocked_by"])
+	ret = m.call(t, http.MethodGet, "/api/trash", nil)
+	assert.Equal(t, []any{}, ret["tasks"])
+
+	// "Done" of a one-off task and adding a task.
+	assert.Empty(t, m.call(t, http.MethodPost, "/api/task/done?id="+repotting+"&force=true", nil))
+	ret = m.call(t, http.MethodPost, "/api/task", map[string]any{"date": today, "title": "Лишняя"})
+	extra := fmt.Sprint(ret["id"])
+	ret = m.call(t, http.MethodPost, "/api/undo", nil)
+	assert.Equal(t, map[string]any{"action": "add", "id": extra}, ret)
+	ret = m.call(t, http.MethodGet, "/api/task?id="+extra, nil)
+	assert.NotEmpty(t, ret["error"])
+	ret = m.call(t, http.MethodPost, "/api/undo", nil)
+	assert.Equal(t, map[string]any{"action": "done", "id": repotting}, ret)
+	ret = m.call(t, http.MethodGet, "/api/tasks", nil)
+	assert.ElementsMatch(t, []string{"Полив", "Пересадка"}, listTitles(ret))
+	ret = m.call(t, http.MethodGet, "/api/task?id="+repotting, nil)
+	assert.Equal(t, []any{watering}, ret["blocked_by"])
+	ret = m.call(t, http.MethodGet, "/api/trash", nil)
+	assert.Equal(t, []any{}, ret["tasks"])
+
+	// A change made after an action (here by another client) makes the action not undoable.
+	ret = m.call(t, http.MethodPut, "/api/task", map[string]any{
+		"id": watering, "date": today, "title": "Полив цветов", "repeat": "d 7",
+		"version": taskVersion(t, m, watering),
+	})
+	assert.Empty(t, ret)
+	task, err := store.Get(watering)
+	assert.NoError(t, err)
+	task.Comment = "по вторникам"
+	assert.NoError(t, store.Update(task))
+	ret = m.call(t, http.MethodPost, "/api/undo", nil)
+	assert.Equal(t, "Задача изменена после этого действия: отменить его нельзя", ret["error"])
+	ret = m.call(t, http.MethodGet, "/api/task?id="+watering, nil)
+	assert.Equal(t, "Полив цветов", ret["title"])
+	assert.Equal(t, "по вторникам", ret["comment"])
+
+	// Older actions are still on the stack, but the tasks have changed since they were added.
+	ret = m.call(t, http.MethodPost, "/api/undo", nil)
+	assert.Equal(t, "Задача изменена после этого действия: отменить его нельзя", ret["error"])
+	ret = m.call(t, http.MethodPost, "/api/undo", nil)
+	assert.Equal(t, "Задача изменена после этого действия: отменить его нельзя", ret["error"])
+	ret = m.call(t, http.MethodPost, "/api/undo", nil)
+	assert.Equal(t, "Нет действий для отмены", ret["error"])
+	ret = m.call(t, http.MethodGet, "/api/tasks", nil)
+	assert.ElementsMatch(t, []string{"Полив цветов", "Пересадка"}, listTitles(ret))
+
+	// Adding a task is undone while the task is unchanged.
+	ret = m.call(t, http.MethodPost, "/api/task", map[string]any{"date": today, "title": "Лишняя"})
+	extra = fmt.Sprint(ret["id"])
+	ret = m.call(t, http.MethodPost, "/api/undo", nil)
+	assert.Equal(t, map[string]any{"action": "add", "id": extra}, ret)
+	ret = m.call(t, http.MethodGet, "/api/trash", nil)
+	assert.Equal(t, []any{}, ret["tasks"])
+
+	testRevert(t, store)
+}
+
+// testRevert checks that the store reverts an action only at the version the action
+// left the task at, and that a refused revert changes nothing.
+func testRevert(t *testing.T, store db.TaskStore) {
+	today := time.Now().Format(`20060102`)
+	id, err := store.Add(&db.Task{Date: today, Title: "Уборка", Repeat: "d 1",
+		Checklist: []*db.ChecklistItem{{Title: "Пыль"}}})
+	assert.NoError(t, err)
+	task := fmt.Sprint(id)
+	id, err = store.Add(&db.Task{Date: today, Title: "Гости"})
+	assert.NoError(t, err)
+	guests := fmt.Sprint(id)
+	assert.NoError(t, store.Link(guests, task))
+
+	old, err := store.Get(task)
+	if !assert.NoError(t, err) {
+		return
+	}
+	next := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+	completion, err := store.Complete(task, old.Version, next, "", time.Now())
+	assert.NoError(t, err)
+	completionID := fmt.Sprint(completion)
+
+	// A stale version leaves the task, its links and the history as they are.
+	err = store.RevertDone(old, []string{guests}, completionID, old.Version)
+	assert.EqualError(t, err, "версия задачи устарела")
+	done, err := store.Get(task)
+	assert.NoError(t, err)
+	assert.Equal(t, next, done.Date)
+	history, err := store.History(task)
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+	dependents, err := store.Dependents(task)
+	assert.NoError(t, err)
+	assert.Empty(t, dependents)
+
+	assert.NoError(t, store.RevertDone(old, []string{guests}, completionID, done.Version))
+	reverted, err := store.Get(task)
+	assert.NoError(t, err)
+	assert.Equal(t, today, reverted.Date)
+	assert.Greater(t, reverted.Version, done.Version)
+	history, err = store.History(task)
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+	dependents, err = store.Dependents(task)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{guests}, dependents)
+	assert.EqualError(t, store.RevertDone(old, nil, completionID, done.Version), "версия задачи устарела")
+
+	// A task restored from the trash by someone else is not reverted again.
+	assert.NoError(t, store.Delete(task))
+	assert.NoError(t, store.Restore(task))
+	assert.EqualError(t, store.RevertDelete(reverted, nil, reverted.Version+1), "версия задачи устарела")
+
+	entries, err := store.AuditLog(db.AuditFilter{TaskID: task, Action: "undo"})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	current, err := store.Get(task)
+	assert.NoError(t, err)
+	assert.NoError(t, store.RevertAdd(task, current.Version))
+	assert.EqualError(t, store.RevertAdd(task, current.Version), "задача не найдена")
+	assert.NoError(t, store.Delete(guests))
+	assert.NoError(t, store.Purge(guests))
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/MaximK0valev/go-task-scheduler/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskVersion(t *testing.T) {
+	forEachStore(t, testTaskVersion)
+}
+
+// testTaskVersion runs the optimistic concurrency scenario against an empty store.
+func testTaskVersion(t *testing.T, store db.TaskStore) {
+	m := newMemoryAPI(t, store)
+	defer m.srv.Close()
+
+	today := time.Now().Format(`20060102`)
+	ret := m.call(t, http.MethodPost, "/api/task", map[string]any{"date": today, "title": "Отчёт", "repeat": "d 1"})
+	id := fmt.Sprint(ret["id"])
+
+	status, etag, ret := m.callIfMatch(t, http.MethodGet, "/api/task?id="+id, "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `"1"`, etag)
+	assert.Equal(t, "1", ret["version"])
+
+	// The edited version is required.
+	edit := map[string]any{"id": id, "date": today, "title": "Отчёт за месяц", "repeat": "d 1"}
+	status, _, ret = m.callIfMatch(t, http.MethodPut, "/api/task", "", edit)
+	assert.Equal(t, http.StatusPreconditionRequired, status)
+	assert.NotEmpty(t, ret["error"])
+	status, _, ret = m.callIfMatch(t, http.MethodPut, "/api/task", "abc", edit)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.NotEmpty(t, ret["error"])
+
+	// The first tab saves, the second one gets the current copy instead of overwriting it.
+	status, etag, ret = m.callIfMatch(t, http.MethodPut, "/api/task", `"1"`, edit)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, ret)
+	assert.Equal(t, `"2"`, etag)
+
+	stale := map[string]any{"id": id, "date": today, "title": "Отчёт за год", "repeat": "d 1"}
+	status, etag, ret = m.callIfMatch(t, http.MethodPut, "/api/task", `"1"`, stale)
+	assert.Equal(t, http.StatusPreconditionFailed, status)
+	assert.NotEmpty(t, ret["error"])
+	assert.Equal(t, `"2"`, etag)
+	current, _ := ret["task"].(map[string]any)
+	assert.Equal(t, "Отчёт за месяц", current["title"])
+	assert.Equal(t, "2", current["version"])
+
+	stale["version"] = "1"
+	status, _, ret = m.callIfMatch(t, http.MethodPut, "/api/task", "", stale)
+	assert.Equal(t, http.StatusConflict, status)
+	current, _ = ret["task"].(map[string]any)
+	assert.Equal(t, "Отчёт за месяц", current["title"])
+	ret = m.call(t, http.MethodGet, "/api/task?id="+id, nil)
+	assert.Equal(t, "Отчёт за месяц", ret["title"])
+
+	// Any change of the task fields makes the old version stale, e.g. marking it as done.
+	assert.Empty(t, m.call(t, http.MethodPost, "/api/task/done?id="+id, nil))
+	assert.Equal(t, "3", taskVersion(t, m, id))
+	stale["version"] = "2"
+	status, _, _ = m.callIfMatch(t, http.MethodPut, "/api/task", "", stale)
+	assert.Equal(t, http.StatusConflict, status)
+	stale["version"] = "3"
+	status, etag, _ = m.callIfMatch(t, http.MethodPut, "/api/task", "", stale)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `"4"`, etag)
+
+	// "*" overwrites any version; undo restores the previous fields whatever the version is.
+	status, _, _ = m.callIfMatch(t, http.MethodPut, "/api/task", "*", edit)
+	assert.Equal(t, http.StatusOK, status)
+	ret = m.call(t, http.MethodPost, "/api/undo", nil)
+	assert.Equal(t, "update", ret["action"])
+	ret = m.call(t, http.MethodGet, "/api/task?id="+id, nil)
+	assert.Equal(t, "Отчёт за год", ret["title"])
+	assert.Equal(t, "6", ret["version"])
+
+	status, _, _ = m.callIfMatch(t, http.MethodPut, "/api/task", `"1"`, map[string]any{"id": "100", "title": "Нет такой"})
+	assert.Equal(t, http.StatusNotFound, status)
+}
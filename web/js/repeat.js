@@ -0,0 +1,105 @@
+// Repeat rules the task dialog in scripts.min.js cannot edit.
+//
+// The compiled dialog rebuilds the rule from its controls on every save and only
+// knows "d N", "w <days>", "m <days> [months]" and "y". Weekly intervals ("w 1,4 /2"),
+// ordinal weekdays ("m 2#2"), working days ("b 5") and RRULE would be rewritten or
+// dropped, so this script keeps the rule of the opened task as it was loaded unless
+// the repeat controls were changed, and shows a rule the dialog cannot display
+// as read-only text.
+(function () {
+    "use strict";
+
+    // editable matches the rules the dialog displays as they are.
+    const editable = /^(|d \d+|w [1-7](,[1-7])*|m -?\d+(,-?\d+)*( \d+(,\d+)*)?|y)$/;
+
+    // loaded is the task opened in the dialog; touched is set once its repeat controls change.
+    let loaded = null;
+    let touched = false;
+
+    function isTaskGet(config) {
+        return config.method === "get" && /^api\/task\?/.test(config.url);
+    }
+
+    function isTaskUpdate(config) {
+        return config.method === "put" && /^api\/task$/.test(config.url);
+    }
+
+    function taskData(config) {
+        return typeof config.data === "string" ? JSON.parse(config.data) : config.data;
+    }
+
+    axios.interceptors.request.use((config) => {
+        const data = isTaskUpdate(config) && taskData(config);
+        if (data && loaded && data.id === loaded.id && !touched) {
+            config.data = { ...data, repeat: loaded.repeat };
+        }
+        return config;
+    });
+
+    axios.interceptors.response.use((response) => {
+        const data = response.data;
+        if (isTaskGet(response.config) && data && data.id && !data.error) {
+            loaded = { id: data.id, repeat: data.repeat || "" };
+            touched = false;
+            setTimeout(syncEditor, 0);
+        }
+        return response;
+    });
+
+    // repeatColumn returns the dialog column with the repeat controls, if a task dialog is open.
+    function repeatColumn() {
+        for (const label of document.querySelectorAll(".modal .dialog .form-label")) {
+            if (label.textContent.trim() === "Правило повторения") {
+                return label.closest(".form-input").parentElement;
+            }
+        }
+        return null;
+    }
+
+    // syncEditor shows the loaded rule as read-only text if the dialog cannot display it.
+    function syncEditor() {
+        const column = repeatColumn();
+        if (!column) {
+            return;
+        }
+        let field = document.getElementById("task-repeat-raw");
+        if (!loaded || editable.test(loaded.repeat)) {
+            if (field) {
+                field.remove();
+            }
+            return;
+        }
+        if (!field) {
+            field = document.createElement("div");
+            field.id = "task-repeat-raw";
+            field.className = "form-input";
+            field.innerHTML = '<div class="form-label">Текущее правило (сохранится без изменений)</div>' +
+                '<input class="input" readonly>';
+            column.querySelector(".form-input").after(field);
+        }
+        field.querySelector("input").value = loaded.repeat;
+    }
+
+    // Any change of the repeat controls means the rule built by the dialog is meant to be saved.
+    for (const type of ["input", "change"]) {
+        document.addEventListener(type, (e) => {
+            const column = repeatColumn();
+            if (column && column.contains(e.target) && !e.target.closest("#task-repeat-raw")) {
+                touched = true;
+                const field = document.getElementById("task-repeat-raw");
+                if (field) {
+                    field.remove();
+                }
+            }
+        }, true);
+    }
+
+    // A new task starts with the rule chosen in the dialog.
+    document.addEventListener("click", (e) => {
+        const button = e.target.closest("button");
+        if (button && button.textContent.trim() === "Добавить задачу") {
+            loaded = null;
+            touched = false;
+        }
+    }, true);
+})();
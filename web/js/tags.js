@@ -0,0 +1,241 @@
+// Task tags for the UI in scripts.min.js.
+//
+// The compiled application knows nothing about tags, so this script extends it
+// through axios interceptors and DOM observation:
+//   - the tag bar under the top navigation filters the list (?tag=) and manages tags;
+//   - tags are shown as #labels after task titles in the list;
+//   - the task dialog gets a "Теги" field that is sent with POST/PUT /api/task.
+(function () {
+    "use strict";
+
+    const dialogTitles = ["Карточка задачи", "Добавить новую задачу"];
+
+    let activeTag = "";
+    let editorTags = [];
+    let tags = [];
+
+    function isTasksList(config) {
+        return config.method === "get" && /^api\/tasks(\?|$)/.test(config.url);
+    }
+
+    function isTaskSave(config) {
+        return (config.method === "post" || config.method === "put") && /^api\/task$/.test(config.url);
+    }
+
+    function isTaskGet(config) {
+        return config.method === "get" && /^api\/task\?/.test(config.url);
+    }
+
+    function parseTags(value) {
+        return value.split(/[\s,]+/).map((t) => t.replace(/^#/, "").toLowerCase()).filter((t) => t !== "");
+    }
+
+    axios.interceptors.request.use((config) => {
+        if (isTasksList(config) && activeTag !== "") {
+            config.url += (config.url.includes("?") ? "&" : "?") + "tag=" + encodeURIComponent(activeTag);
+        }
+        if (isTaskSave(config)) {
+            const input = document.getElementById("task-tags");
+            if (input) {
+                config.data = { ...config.data, tags: parseTags(input.value) };
+            }
+        }
+        return config;
+    });
+
+    axios.interceptors.response.use((response) => {
+        const config = response.config;
+        const data = response.data;
+        if (data && !data.error) {
+            if (isTasksList(config) && data.tasks) {
+                for (const task of data.tasks) {
+                    if (task.tags) {
+                        task.title += " " + task.tags.map((t) => "#" + t).join(" ");
+                    }
+                }
+            } else if (isTaskGet(config)) {
+                editorTags = data.tags || [];
+                setTimeout(() => syncEditor(true), 0);
+            } else if (config.method !== "get" && /^api\/task/.test(config.url)) {
+                loadTags();
+            }
+        }
+        return response;
+    });
+
+    // taskDialog returns the content element of the open task dialog, if any.
+    function taskDialog() {
+        for (const dialog of document.querySelectorAll(".modal .dialog")) {
+            const header = dialog.querySelector(".dialog-header h4");
+            if (header && dialogTitles.includes(header.textContent.trim())) {
+                return dialog.querySelector(".dialog-content");
+            }
+        }
+        return null;
+    }
+
+    // syncEditor adds the tags field to the task dialog if it is missing.
+    // A new field, or any field if reset is set, is filled with editorTags.
+    function syncEditor(reset) {
+        const content = taskDialog();
+        if (!content) {
+            return;
+        }
+        let input = document.getElementById("task-tags");
+        if (!input) {
+            const comment = content.querySelector("textarea");
+            if (!comment) {
+                return;
+            }
+            const field = document.createElement("div");
+            field.className = "form-input";
+            field.innerHTML = '<div class="form-label">Теги</div>' +
+                '<input id="task-tags" class="input" placeholder="работа, дом" list="task-tags-list">' +
+                '<datalist id="task-tags-list"></datalist>';
+            comment.closest(".form-input").after(field);
+            input = document.getElementById("task-tags");
+            renderDatalist();
+            reset = true;
+        }
+        if (reset) {
+            input.value = editorTags.join(", ");
+        }
+    }
+
+    function renderDatalist() {
+        const list = document.getElementById("task-tags-list");
+        if (list) {
+            list.innerHTML = "";
+            for (const tag of tags) {
+                const option = document.createElement("option");
+                option.value = tag.name;
+                list.appendChild(option);
+            }
+        }
+    }
+
+    function showError(err) {
+        const message = err.response && err.response.data && err.response.data.error ? err.response.data.error : err;
+        if (String(message).includes("401")) {
+            window.location = "/login.html";
+            return;
+        }
+        alert(message);
+    }
+
+    // refreshList reloads the task list with the current search text.
+    function refreshList() {
+        for (const button of document.querySelectorAll("#app button")) {
+            if (button.textContent.trim() === "Найти") {
+                button.click();
+                return;
+            }
+        }
+    }
+
+    function loadTags() {
+        axios.get("api/tags").then((response) => {
+            tags = response.data.tags || [];
+            if (activeTag !== "" && !tags.some((t) => t.name === activeTag)) {
+                activeTag = "";
+                refreshList();
+            }
+            renderBar();
+            renderDatalist();
+        }).catch(showError);
+    }
+
+    function chip(text, title, active, onclick) {
+        const el = document.createElement("button");
+        el.className = "tag" + (active ? " active" : "");
+        el.textContent = text;
+        el.title = title;
+        el.onclick = onclick;
+        return el;
+    }
+
+    function renderBar() {
+        const bar = document.getElementById("tagbar");
+        if (!bar) {
+            return;
+        }
+        bar.innerHTML = "";
+        bar.appendChild(chip("все", "Показать все задачи", activeTag === "", () => selectTag("")));
+        for (const tag of tags) {
+            const el = chip("#" + tag.name + " " + tag.tasks, "Показать задачи с тегом", activeTag === tag.name,
+                () => selectTag(activeTag === tag.name ? "" : tag.name));
+            const rename = document.createElement("span");
+            rename.className = "tag-action";
+            rename.textContent = "✎";
+            rename.title = "Переименовать";
+            rename.onclick = (e) => { e.stopPropagation(); renameTag(tag); };
+            const remove = document.createElement("span");
+            remove.className = "tag-action";
+            remove.textContent = "×";
+            remove.title = "Удалить тег";
+            remove.onclick = (e) => { e.stopPropagation(); deleteTag(tag); };
+            el.append(rename, remove);
+            bar.appendChild(el);
+        }
+        bar.appendChild(chip("+", "Новый тег", false, addTag));
+    }
+
+    function selectTag(name) {
+        activeTag = name;
+        renderBar();
+        refreshList();
+    }
+
+    function addTag() {
+        const name = prompt("Новый тег");
+        if (name) {
+            axios.post("api/tags", { name: name }).then(loadTags).catch(showError);
+        }
+    }
+
+    function renameTag(tag) {
+        const name = prompt("Новое имя тега", tag.name);
+        if (name && name !== tag.name) {
+            axios.put("api/tags", { id: tag.id, name: name }).then(() => {
+                if (activeTag === tag.name) {
+                    activeTag = name.replace(/^#/, "").trim().toLowerCase();
+                }
+                loadTags();
+                refreshList();
+            }).catch(showError);
+        }
+    }
+
+    function deleteTag(tag) {
+        if (confirm("Удалить тег #" + tag.name + "? Задачи останутся без него.")) {
+            axios.delete("api/tags?id=" + tag.id).then(() => {
+                loadTags();
+                refreshList();
+            }).catch(showError);
+        }
+    }
+
+    // The application renders asynchronously: add the tag bar once the top
+    // navigation exists and the tags field whenever a task dialog opens.
+    new MutationObserver(() => {
+        const nav = document.querySelector(".topnav");
+        if (nav && !document.getElementById("tagbar")) {
+            const bar = document.createElement("div");
+            bar.id = "tagbar";
+            bar.className = "tagbar";
+            nav.after(bar);
+            loadTags();
+        }
+        if (!document.getElementById("task-tags")) {
+            syncEditor(false);
+        }
+    }).observe(document.documentElement, { childList: true, subtree: true });
+
+    document.addEventListener("click", (e) => {
+        const button = e.target.closest("button");
+        if (button && button.textContent.trim() === "Добавить задачу") {
+            editorTags = activeTag !== "" ? [activeTag] : [];
+            setTimeout(() => syncEditor(true), 0);
+        }
+    }, true);
+})();
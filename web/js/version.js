@@ -0,0 +1,62 @@
+// Task versions for the UI in scripts.min.js.
+//
+// PUT /api/task requires the version of the task being edited. The compiled
+// application does not know about versions, so this script remembers the version
+// of every task opened in the dialog and adds it to the update request; if the
+// version of a task is not known yet, it is loaded before the update. When the
+// task was changed elsewhere in the meantime (409/412) or the version is still
+// missing (428), the conflict is shown as an error.
+(function () {
+    "use strict";
+
+    const versions = {};
+
+    function isTaskUpdate(config) {
+        return config.method === "put" && /^api\/task$/.test(config.url);
+    }
+
+    function taskData(config) {
+        return typeof config.data === "string" ? JSON.parse(config.data) : config.data;
+    }
+
+    axios.interceptors.request.use(async (config) => {
+        const data = isTaskUpdate(config) && taskData(config);
+        if (!data || !data.id || data.version) {
+            return config;
+        }
+        if (!versions[data.id]) {
+            // The response interceptor below remembers the version.
+            await axios.get("api/task?id=" + encodeURIComponent(data.id)).catch(() => {});
+        }
+        if (versions[data.id]) {
+            config.data = { ...data, version: versions[data.id] };
+        }
+        return config;
+    });
+
+    axios.interceptors.response.use((response) => {
+        const data = response.data;
+        if (response.config.method === "get" && /^api\/task\?/.test(response.config.url) && data && data.id) {
+            versions[data.id] = data.version;
+        }
+        // A saved task gets the version from the ETag header.
+        const etag = response.headers && response.headers.etag;
+        if (isTaskUpdate(response.config) && etag) {
+            versions[taskData(response.config).id] = etag.replace(/^W\//, "").replace(/"/g, "");
+        }
+        return response;
+    }, (error) => {
+        const response = error.response;
+        if (!response || !error.config || !isTaskUpdate(error.config) || !response.data || !response.data.error) {
+            return Promise.reject(error);
+        }
+        switch (response.status) {
+        case 409:
+        case 412:
+            return { ...response, data: { error: response.data.error + ". Откройте задачу заново, чтобы увидеть изменения." } };
+        case 428:
+            return { ...response, data: { error: response.data.error + ". Откройте задачу заново и повторите сохранение." } };
+        }
+        return Promise.reject(error);
+    });
+})();